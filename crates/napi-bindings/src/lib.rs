@@ -5,6 +5,7 @@ use napi_derive::napi;
 use synapse_parser::{
     LanguageManager as RustLanguageManager,
     LegacyASTParser as RustParser,
+    ParseResult,
 };
 
 /// NAPI AST Parser（旧版 - 保持向后兼容）
@@ -224,12 +225,44 @@ impl LanguageManager {
             .collect()
     }
 
+    /// 增量重新解析
+    ///
+    /// `previous_json` 为上次 parse_file / reparse_file 返回的解析结果 JSON，
+    /// 返回 `{ result, diff }`，diff 包含 added / removed / modified / moved
+    #[napi]
+    pub fn reparse_file(&mut self, previous_json: String, source_code: String) -> Result<String> {
+        let previous: ParseResult = serde_json::from_str(&previous_json)
+            .map_err(|e| Error::from_reason(e.to_string()))?;
+
+        let update = self
+            .inner
+            .reparse_file(&previous, &source_code)
+            .map_err(|e| Error::from_reason(e))?;
+
+        serde_json::to_string(&update).map_err(|e| Error::from_reason(e.to_string()))
+    }
+
+    /// 释放文件的增量解析缓存
+    #[napi]
+    pub fn forget_file(&mut self, file_path: String) {
+        self.inner.forget_file(&file_path);
+    }
+
     /// 根据文件路径猜测语言
     #[napi]
     pub fn guess_language(&self, file_path: String) -> Option<String> {
         self.inner.guess_language(&file_path).map(|lang| format!("{}", lang))
     }
 
+    /// 根据文件路径与内容检测语言（扩展名优先，shebang / package 启发式兜底）
+    #[napi]
+    pub fn detect_language(&self, file_path: String, source_code: String) -> Option<String> {
+        self.inner
+            .detect_language(&file_path, &source_code)
+            .language()
+            .map(|lang| format!("{}", lang))
+    }
+
     /// 获取支持的语言列表
     #[napi]
     pub fn get_supported_languages() -> Vec<String> {
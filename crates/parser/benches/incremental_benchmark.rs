@@ -0,0 +1,50 @@
+use criterion::{black_box, criterion_group, criterion_main, Criterion};
+use synapse_parser::LanguageManager;
+
+/// 生成包含大量函数的 Go 文件
+fn generate_go_source(functions: usize) -> String {
+    let mut source = String::from("package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n");
+    for i in 0..functions {
+        source.push_str(&format!(
+            "\n// Func{i} 格式化第 {i} 个值\nfunc Func{i}(v string) string {{\n\treturn fmt.Sprintf(\"%d:%s\", {i}, strings.TrimSpace(v))\n}}\n",
+        ));
+    }
+    source
+}
+
+fn bench_reparse(c: &mut Criterion) {
+    let original = generate_go_source(1000);
+    // 只改动中间一个函数
+    let changed = original.replacen("\"%d:%s\", 500,", "\"%d=%s\", 500,", 1);
+
+    let mut group = c.benchmark_group("reparse_1000_functions");
+
+    group.bench_function("full", |b| {
+        let mut manager = LanguageManager::new();
+        let mut flip = false;
+        b.iter(|| {
+            flip = !flip;
+            let source = if flip { &changed } else { &original };
+            black_box(manager.parse_file("big.go", source).unwrap());
+        });
+    });
+
+    group.bench_function("incremental", |b| {
+        let mut manager = LanguageManager::new();
+        let mut previous = manager.parse_file("big.go", &original).unwrap();
+        previous = manager.reparse_file(&previous, &original).unwrap().result;
+        let mut flip = false;
+        b.iter(|| {
+            flip = !flip;
+            let source = if flip { &changed } else { &original };
+            let update = manager.reparse_file(&previous, source).unwrap();
+            black_box(&update.diff);
+            previous = update.result;
+        });
+    });
+
+    group.finish();
+}
+
+criterion_group!(benches, bench_reparse);
+criterion_main!(benches);
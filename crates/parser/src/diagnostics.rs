@@ -0,0 +1,74 @@
+use tree_sitter::Node;
+
+use crate::strategies::get_node_text;
+use crate::symbols::node_span;
+use crate::types::Diagnostic;
+
+/// 诊断消息中引用的源码片段最多保留的字符数
+const SNIPPET_MAX_CHARS: usize = 40;
+
+/// 收集语法树中的语法错误：ERROR 节点（无法识别的源码）与 MISSING 节点（tree-sitter 补全的缺失符号）
+///
+/// 只进入 `has_error()` 的子树；ERROR 节点整体报告一次，不再展开其内部
+pub fn collect_diagnostics(root: Node, source_code: &str) -> Vec<Diagnostic> {
+    let mut diagnostics = Vec::new();
+    if root.has_error() {
+        collect(root, source_code, &mut diagnostics);
+    }
+    diagnostics
+}
+
+fn collect(node: Node, source_code: &str, diagnostics: &mut Vec<Diagnostic>) {
+    if node.is_error() {
+        let message = match snippet(get_node_text(node, source_code)) {
+            Some(snippet) => format!("Syntax error: unexpected `{}`", snippet),
+            None => "Syntax error".to_string(),
+        };
+        diagnostics.push(Diagnostic {
+            message,
+            span: node_span(node),
+        });
+        return;
+    }
+    if node.is_missing() {
+        diagnostics.push(Diagnostic {
+            message: format!("Missing `{}`", node.kind()),
+            span: node_span(node),
+        });
+        return;
+    }
+
+    let mut cursor = node.walk();
+    for child in node.children(&mut cursor) {
+        if child.has_error() {
+            collect(child, source_code, diagnostics);
+        }
+    }
+}
+
+/// 错误源码的第一行（去除首尾空白），超长时截断并以 `...` 结尾
+fn snippet(text: &str) -> Option<String> {
+    let line = text.lines().map(str::trim).find(|line| !line.is_empty())?;
+    if line.chars().count() <= SNIPPET_MAX_CHARS {
+        return Some(line.to_string());
+    }
+    let truncated: String = line.chars().take(SNIPPET_MAX_CHARS).collect();
+    Some(format!("{}...", truncated))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_snippet() {
+        assert_eq!(snippet("  counter := 0\n").as_deref(), Some("counter := 0"));
+        assert_eq!(snippet("\n\n  }\nfunc f() {}").as_deref(), Some("}"));
+        assert_eq!(snippet("   \n"), None);
+
+        let long = "用户".repeat(30);
+        let truncated = snippet(&long).unwrap();
+        assert_eq!(truncated.chars().count(), SNIPPET_MAX_CHARS + 3);
+        assert!(truncated.ends_with("..."));
+    }
+}
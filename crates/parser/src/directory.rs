@@ -0,0 +1,247 @@
+use std::fs;
+use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicBool, AtomicUsize, Ordering};
+use std::thread;
+
+use serde::{Deserialize, Serialize};
+
+use crate::ext_to_lang::{detect_language, guess_language};
+use crate::language_manager::LanguageManager;
+use crate::options::{is_test_file, ParseOptions};
+use crate::types::ParseResult;
+
+/// 目录解析中单个文件的结果：成功时 result 有值，失败时 error 有值，未解析时 skipped 有值
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct FileResult {
+    /// 相对于解析根目录的路径（`/` 分隔）
+    pub path: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub result: Option<ParseResult>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub skipped: Option<SkipReason>,
+}
+
+/// 支持的源文件未被解析的原因
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase", tag = "reason")]
+pub enum SkipReason {
+    /// 文件大小超过 `ParseOptions::max_file_bytes`
+    #[serde(rename_all = "camelCase")]
+    TooLarge { size: u64, limit: u64 },
+    /// 内容开头包含 NUL 字节，视为二进制文件
+    Binary,
+}
+
+/// 判断二进制内容时检查的前缀长度（与 git 的启发式一致）
+const BINARY_SNIFF_BYTES: usize = 8000;
+
+/// 并发解析目录下所有支持的文件
+///
+/// - `workers` 个线程共享一个文件队列，每个线程持有独立的 LanguageManager
+/// - 不支持的语言直接跳过，`options.exclude_tests` 时跳过测试文件；单个文件的读取/解析失败记录在 FileResult.error 中，不中断整体
+/// - 无法读取的子目录或目录项同样记录为带 error 的 FileResult（path 为该目录），遍历继续；只有根目录不可读时返回错误
+/// - 超过 `options.max_file_bytes` 或含 NUL 字节（二进制）的源文件不解析，原因记录在 FileResult.skipped 中；
+///   无扩展名的文件只有在内容被识别为源码时才会出现在结果里
+/// - `cancel` 置位后尽快停止并返回错误
+/// - 结果按路径排序，与线程调度无关
+///
+/// 以 `.` 开头的隐藏目录（如 `.git`）不会被遍历，符号链接不跟随
+pub fn parse_dir(
+    root: impl AsRef<Path>,
+    workers: usize,
+    options: &ParseOptions,
+    cancel: &AtomicBool,
+) -> Result<Vec<FileResult>, String> {
+    let root = root.as_ref();
+    let mut files = Vec::new();
+    let mut walk_errors = Vec::new();
+    collect_files(root, root, cancel, &mut files, &mut walk_errors)?;
+    if options.exclude_tests {
+        files.retain(|(_, relative)| !is_test_file(relative));
+    }
+
+    let workers = workers.clamp(1, files.len().max(1));
+    let next = AtomicUsize::new(0);
+
+    let mut results = thread::scope(|scope| {
+        let handles: Vec<_> = (0..workers)
+            .map(|_| {
+                scope.spawn(|| {
+                    let mut manager = LanguageManager::new();
+                    let mut local = Vec::new();
+                    while !cancel.load(Ordering::Relaxed) {
+                        let index = next.fetch_add(1, Ordering::Relaxed);
+                        let Some((full_path, relative)) = files.get(index) else {
+                            break;
+                        };
+                        if let Some(result) = parse_entry(&mut manager, full_path, relative, options) {
+                            local.push(result);
+                        }
+                    }
+                    local
+                })
+            })
+            .collect();
+
+        handles
+            .into_iter()
+            .map(|handle| handle.join().map_err(|_| "Parse worker panicked".to_string()))
+            .collect::<Result<Vec<_>, String>>()
+    })?
+    .into_iter()
+    .flatten()
+    .collect::<Vec<_>>();
+
+    if cancel.load(Ordering::Relaxed) {
+        return Err("Directory parsing cancelled".to_string());
+    }
+
+    results.extend(walk_errors);
+    results.sort_by(|a, b| a.path.cmp(&b.path));
+    Ok(results)
+}
+
+/// 递归收集文件：(绝对路径, 相对路径)
+///
+/// 子目录读取失败记入 errors 后继续遍历；只有取消与根目录不可读会返回 Err
+fn collect_files(
+    root: &Path,
+    dir: &Path,
+    cancel: &AtomicBool,
+    files: &mut Vec<(PathBuf, String)>,
+    errors: &mut Vec<FileResult>,
+) -> Result<(), String> {
+    if cancel.load(Ordering::Relaxed) {
+        return Err("Directory parsing cancelled".to_string());
+    }
+
+    let walk_error = |e: std::io::Error| {
+        let relative = relative_path(root, dir);
+        FileResult {
+            error: Some(format!("Failed to read directory {}: {}", relative, e)),
+            path: relative,
+            result: None,
+            skipped: None,
+        }
+    };
+
+    let entries = match fs::read_dir(dir) {
+        Ok(entries) => entries,
+        Err(e) if dir == root => {
+            return Err(format!("Failed to read directory {}: {}", dir.display(), e));
+        }
+        Err(e) => {
+            errors.push(walk_error(e));
+            return Ok(());
+        }
+    };
+
+    for entry in entries {
+        let entry = match entry {
+            Ok(entry) => entry,
+            Err(e) => {
+                errors.push(walk_error(e));
+                continue;
+            }
+        };
+        let path = entry.path();
+        let Ok(file_type) = entry.file_type() else {
+            continue;
+        };
+
+        if file_type.is_dir() {
+            if !entry.file_name().to_string_lossy().starts_with('.') {
+                collect_files(root, &path, cancel, files, errors)?;
+            }
+        } else if file_type.is_file() {
+            files.push((path.clone(), relative_path(root, &path)));
+        }
+    }
+
+    Ok(())
+}
+
+/// 相对路径统一使用 `/` 分隔，保证跨平台排序一致
+fn relative_path(root: &Path, path: &Path) -> String {
+    path.strip_prefix(root)
+        .unwrap_or(path)
+        .components()
+        .map(|c| c.as_os_str().to_string_lossy())
+        .collect::<Vec<_>>()
+        .join("/")
+}
+
+/// 解析单个文件；不支持的文件返回 None
+fn parse_entry(
+    manager: &mut LanguageManager,
+    full_path: &Path,
+    relative: &str,
+    options: &ParseOptions,
+) -> Option<FileResult> {
+    // 有扩展名但不认识的文件（图片、锁文件等）不读取内容；无扩展名的文件靠内容启发式判断
+    let known_extension = guess_language(relative).is_some();
+    if !known_extension && full_path.extension().is_some() {
+        return None;
+    }
+
+    let failed = |e: String| {
+        Some(FileResult {
+            path: relative.to_string(),
+            result: None,
+            error: Some(format!("Failed to read {}: {}", relative, e)),
+            skipped: None,
+        })
+    };
+    let skipped = |reason: SkipReason| {
+        // 无扩展名的大文件/二进制文件（可执行文件、数据文件）不是源码，不报告
+        known_extension.then(|| FileResult {
+            path: relative.to_string(),
+            result: None,
+            error: None,
+            skipped: Some(reason),
+        })
+    };
+
+    // 先看元数据，超限的文件不读取内容
+    let size = match fs::metadata(full_path) {
+        Ok(metadata) => metadata.len(),
+        Err(_) if !known_extension => return None,
+        Err(e) => return failed(e.to_string()),
+    };
+    if options.max_file_bytes > 0 && size > options.max_file_bytes {
+        return skipped(SkipReason::TooLarge { size, limit: options.max_file_bytes });
+    }
+
+    let bytes = match fs::read(full_path) {
+        Ok(bytes) => bytes,
+        Err(_) if !known_extension => return None,
+        Err(e) => return failed(e.to_string()),
+    };
+    if bytes[..bytes.len().min(BINARY_SNIFF_BYTES)].contains(&0) {
+        return skipped(SkipReason::Binary);
+    }
+    let source = match String::from_utf8(bytes) {
+        Ok(source) => source,
+        Err(_) if !known_extension => return None,
+        Err(e) => return failed(e.to_string()),
+    };
+
+    let lang = detect_language(relative, source.as_bytes()).language()?;
+    let (result, error) = match manager.parse_with_language(relative, &source, lang) {
+        Ok(mut result) => {
+            options.apply(&mut result);
+            (Some(result), None)
+        }
+        Err(e) => (None, Some(e)),
+    };
+
+    Some(FileResult {
+        path: relative.to_string(),
+        result,
+        error,
+        skipped: None,
+    })
+}
@@ -83,18 +83,136 @@ lazy_static! {
     };
 }
 
+/// 内容启发式只检查文件开头的字节数
+const DETECT_HEAD_BYTES: usize = 4096;
+
+/// 语言检测结果
+///
+/// 无法可靠判断时返回 Unknown 而不是猜测，由调用方决定是否跳过该文件
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum DetectedLanguage {
+    Known(SupportedLanguage),
+    Unknown,
+}
+
+impl DetectedLanguage {
+    /// 转换为 Option，便于与 guess_language 的结果组合
+    pub fn language(self) -> Option<SupportedLanguage> {
+        match self {
+            Self::Known(lang) => Some(lang),
+            Self::Unknown => None,
+        }
+    }
+}
+
+/// 获取小写的文件扩展名
+fn extension(file_path: &str) -> Option<String> {
+    use std::path::Path;
+
+    Some(Path::new(file_path).extension()?.to_str()?.to_lowercase())
+}
+
 /// 根据文件路径猜测语言
 pub fn guess_language(file_path: &str) -> Option<SupportedLanguage> {
-    use std::path::Path;
-    
-    let ext = Path::new(file_path)
-        .extension()?
-        .to_str()?
-        .to_lowercase();
-    
+    let ext = extension(file_path)?;
     EXT_TO_LANG.get(ext.as_str()).copied()
 }
 
+/// 根据文件路径与内容检测语言
+///
+/// 优先使用扩展名；扩展名缺失或无法识别时，退回到内容启发式
+/// （shebang 解释器、Go/Java 的 `package` 声明）。`.h` 头文件在内容
+/// 明显是 C++ 时识别为 C++
+pub fn detect_language(file_path: &str, content: &[u8]) -> DetectedLanguage {
+    let head = String::from_utf8_lossy(&content[..content.len().min(DETECT_HEAD_BYTES)]);
+
+    if let Some(lang) = guess_language(file_path) {
+        #[cfg(all(feature = "c-lang", feature = "cpp"))]
+        if lang == SupportedLanguage::C && looks_like_cpp(&head) {
+            return DetectedLanguage::Known(SupportedLanguage::Cpp);
+        }
+        return DetectedLanguage::Known(lang);
+    }
+
+    detect_from_shebang(&head)
+        .or_else(|| detect_from_package(&head))
+        .map_or(DetectedLanguage::Unknown, DetectedLanguage::Known)
+}
+
+/// 解析首行 shebang（`#!/usr/bin/env python3`、`#!/usr/bin/node` 等）
+fn detect_from_shebang(head: &str) -> Option<SupportedLanguage> {
+    let line = head.lines().next()?.strip_prefix("#!")?;
+    let mut parts = line.split_whitespace();
+
+    let mut interpreter = parts.next()?.rsplit('/').next()?;
+    if interpreter == "env" {
+        // 跳过 env 的参数，如 `env -S deno run`
+        interpreter = parts.find(|p| !p.starts_with('-'))?;
+    }
+
+    match interpreter {
+        #[cfg(feature = "python")]
+        i if i.starts_with("python") => Some(SupportedLanguage::Python),
+        "node" | "nodejs" | "bun" => Some(SupportedLanguage::JavaScript),
+        "deno" | "ts-node" | "tsx" => Some(SupportedLanguage::TypeScript),
+        _ => None,
+    }
+}
+
+/// 检查第一条非注释语句是否为 `package` 声明：Go 为 `package main`，Java 以分号结尾
+#[allow(unused_variables)]
+fn detect_from_package(head: &str) -> Option<SupportedLanguage> {
+    let mut in_block_comment = false;
+    let line = head.lines().map(str::trim).find(|line| {
+        if in_block_comment {
+            in_block_comment = !line.contains("*/");
+            return false;
+        }
+        if line.starts_with("/*") {
+            in_block_comment = !line.contains("*/");
+            return false;
+        }
+        !line.is_empty() && !line.starts_with("//")
+    })?;
+
+    let name = line.strip_prefix("package")?;
+    if !name.starts_with(char::is_whitespace) {
+        return None;
+    }
+    let name = name.trim();
+
+    #[cfg(feature = "java")]
+    if let Some(java_name) = name.strip_suffix(';') {
+        if !java_name.is_empty() && java_name.split('.').all(is_identifier) {
+            return Some(SupportedLanguage::Java);
+        }
+    }
+
+    #[cfg(feature = "go")]
+    if is_identifier(name) {
+        return Some(SupportedLanguage::Go);
+    }
+
+    None
+}
+
+fn is_identifier(s: &str) -> bool {
+    let mut chars = s.chars();
+    chars.next().map_or(false, |c| c.is_alphabetic() || c == '_')
+        && chars.all(|c| c.is_alphanumeric() || c == '_')
+}
+
+/// `.h` 头文件中出现 C++ 独有语法时视为 C++
+#[cfg(all(feature = "c-lang", feature = "cpp"))]
+fn looks_like_cpp(head: &str) -> bool {
+    head.lines().map(str::trim).any(|line| {
+        line.starts_with("namespace ")
+            || line.starts_with("template")
+            || line.starts_with("class ")
+            || line.contains("std::")
+    })
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -121,4 +239,58 @@ mod tests {
     fn test_guess_unknown() {
         assert_eq!(guess_language("file.unknown"), None);
     }
+
+    #[test]
+    fn test_detect_prefers_extension() {
+        assert_eq!(
+            detect_language("script.ts", b"#!/usr/bin/env node\n"),
+            DetectedLanguage::Known(SupportedLanguage::TypeScript)
+        );
+    }
+
+    #[cfg(feature = "python")]
+    #[test]
+    fn test_detect_shebang() {
+        assert_eq!(
+            detect_language("bin/tool", b"#!/usr/bin/env python3\nprint('hi')\n"),
+            DetectedLanguage::Known(SupportedLanguage::Python)
+        );
+        assert_eq!(
+            detect_language("bin/tool", b"#!/usr/local/bin/node\n"),
+            DetectedLanguage::Known(SupportedLanguage::JavaScript)
+        );
+    }
+
+    #[cfg(all(feature = "go", feature = "java"))]
+    #[test]
+    fn test_detect_package_keyword() {
+        assert_eq!(
+            detect_language("main", b"// Command main\n\n/* demo */\npackage main\n"),
+            DetectedLanguage::Known(SupportedLanguage::Go)
+        );
+        assert_eq!(
+            detect_language("Main", b"package com.example.app;\n"),
+            DetectedLanguage::Known(SupportedLanguage::Java)
+        );
+        assert_eq!(detect_language("notes", b"packages are great\n"), DetectedLanguage::Unknown);
+    }
+
+    #[cfg(all(feature = "c-lang", feature = "cpp"))]
+    #[test]
+    fn test_detect_cpp_header() {
+        assert_eq!(
+            detect_language("util.h", b"#pragma once\nint add(int a, int b);\n"),
+            DetectedLanguage::Known(SupportedLanguage::C)
+        );
+        assert_eq!(
+            detect_language("util.h", b"#pragma once\nnamespace util {\n}\n"),
+            DetectedLanguage::Known(SupportedLanguage::Cpp)
+        );
+    }
+
+    #[test]
+    fn test_detect_unknown() {
+        assert_eq!(detect_language("README", b"hello world\n"), DetectedLanguage::Unknown);
+        assert_eq!(detect_language("data.bin", &[0, 159, 146, 150]), DetectedLanguage::Unknown);
+    }
 }
@@ -187,6 +187,7 @@ impl<'a> CodeEntityExtractor<'a> {
             specifiers,
             file_path: self.file_path.to_string(),
             is_type_only,
+            alias: None,
         })
     }
 
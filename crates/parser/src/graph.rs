@@ -0,0 +1,702 @@
+use std::collections::{HashMap, HashSet};
+
+use serde::{Deserialize, Serialize};
+
+use crate::directory::FileResult;
+use crate::types::{CallRef, Span, Symbol, SymbolKind};
+
+/// 图节点种类
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum GraphNodeKind {
+    File,
+    Function,
+    Method,
+    Type,
+    Interface,
+    Constant,
+    Variable,
+    Field,
+}
+
+/// 图节点；符号节点以 stable_id 为键，可直接用于 NervusDB 的幂等 upsert
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct GraphNode {
+    /// 符号为 stable_id，真正重复的 stable_id（如同一包中的多个 `init`）按出现顺序追加 `#2`、`#3`；
+    /// 文件为 `file:<path>`；字段为 `<类型节点 id>#<字段名>`。符号移到同包的另一个文件时 id 不变，
+    /// 只有 file_path 改变
+    pub id: String,
+    pub kind: GraphNodeKind,
+    pub name: String,
+    pub file_path: String,
+    pub language: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub span: Option<Span>,
+    /// 函数/类型为规范化签名，字段为字段类型；文件为空
+    #[serde(skip_serializing_if = "String::is_empty", default)]
+    pub signature: String,
+    /// 符号源码哈希，写入前可与库中的值比较以跳过未变化的节点；文件与字段为空
+    #[serde(skip_serializing_if = "String::is_empty", default)]
+    pub content_hash: String,
+}
+
+/// 图关系（谓词），与 TS 侧 `GraphPredicate` 的取值一致
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "SCREAMING_SNAKE_CASE")]
+pub enum GraphPredicate {
+    /// 文件 → 顶层函数/类型/接口/常量/变量
+    Defines,
+    /// 类型 → 字段 / 方法；接口 → 嵌入的接口
+    Contains,
+    /// 函数/方法（或变量的初始化表达式）→ 被调用的函数/方法
+    Calls,
+}
+
+/// 图的边，对应一条 `subject predicate object` 事实
+#[derive(Debug, Clone, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct GraphEdge {
+    pub subject: String,
+    pub predicate: GraphPredicate,
+    pub object: String,
+}
+
+/// 将解析结果转换为图节点与边（解析与持久化之间的适配层，不依赖数据库）
+///
+/// - 每个文件一个 File 节点，DEFINES 指向其中的函数与类型；方法挂在所属类型下（CONTAINS），
+///   类型不在已解析的结果中时退回由文件 DEFINES
+/// - 类型的字段为 Field 节点，由类型 CONTAINS
+/// - 调用只在同一作用域内解析（Go 为同目录同包，其他语言为同一文件）：直接调用匹配同名函数，
+///   通过接收者（Go 的接收者名、Python 的 `self`、JS 的 `this`）调用匹配同一类型的方法；
+///   无法解析的调用（标准库、其他包、动态调用）不产生边
+///
+/// 解析失败的文件（`error` 有值）被跳过；同一路径重复输入时只处理第一份。
+/// 节点与边按输入顺序产出并去重，结果是确定的
+pub fn to_graph(results: &[FileResult]) -> (Vec<GraphNode>, Vec<GraphEdge>) {
+    let mut graph = GraphBuilder::default();
+
+    // 先建立作用域内的名字索引，调用与方法的归属可能跨文件（同一 Go 包的多个文件）
+    let mut paths = HashSet::new();
+    let parsed: Vec<_> = results
+        .iter()
+        .filter_map(|file| file.result.as_ref().map(|result| (file, result)))
+        .filter(|(file, _)| paths.insert(file.path.as_str()))
+        .collect();
+    let mut counts = HashMap::new();
+    let node_ids: Vec<Vec<String>> = parsed
+        .iter()
+        .map(|(_, result)| {
+            result
+                .symbols
+                .iter()
+                .map(|symbol| node_id(&mut counts, &symbol.stable_id))
+                .collect()
+        })
+        .collect();
+    let mut index = ScopeIndex::default();
+    for ((file, result), ids) in parsed.iter().zip(&node_ids) {
+        let scope = scope_of(&file.path, result.package.as_deref());
+        for (symbol, id) in result.symbols.iter().zip(ids) {
+            index.insert(&scope, symbol, id);
+        }
+    }
+
+    for ((file, result), ids) in parsed.iter().zip(&node_ids) {
+        let scope = scope_of(&file.path, result.package.as_deref());
+        let file_id = format!("file:{}", file.path);
+        graph.node(GraphNode {
+            id: file_id.clone(),
+            kind: GraphNodeKind::File,
+            name: file
+                .path
+                .rsplit('/')
+                .next()
+                .unwrap_or(&file.path)
+                .to_string(),
+            file_path: file.path.clone(),
+            language: result.language.clone(),
+            span: None,
+            signature: String::new(),
+            content_hash: String::new(),
+        });
+
+        for (symbol, id) in result.symbols.iter().zip(ids) {
+            graph.node(GraphNode {
+                id: id.clone(),
+                kind: match symbol.kind {
+                    SymbolKind::Function => GraphNodeKind::Function,
+                    SymbolKind::Method => GraphNodeKind::Method,
+                    SymbolKind::Type => GraphNodeKind::Type,
+                    SymbolKind::Interface => GraphNodeKind::Interface,
+                    SymbolKind::Constant => GraphNodeKind::Constant,
+                    SymbolKind::Variable => GraphNodeKind::Variable,
+                },
+                name: symbol.name.clone(),
+                file_path: file.path.clone(),
+                language: result.language.clone(),
+                span: Some(symbol.span),
+                signature: symbol.signature.clone(),
+                content_hash: symbol.content_hash.clone(),
+            });
+
+            let owner = symbol.receiver.as_ref().and_then(|receiver| {
+                index
+                    .types
+                    .get(&(scope.clone(), receiver.type_name.clone()))
+            });
+            match owner {
+                Some(type_id) => graph.edge(type_id, GraphPredicate::Contains, id),
+                None => graph.edge(&file_id, GraphPredicate::Defines, id),
+            }
+
+            for field in &symbol.fields {
+                let field_id = format!("{}#{}", id, field.name);
+                graph.node(GraphNode {
+                    id: field_id.clone(),
+                    kind: GraphNodeKind::Field,
+                    name: field.name.clone(),
+                    file_path: file.path.clone(),
+                    language: result.language.clone(),
+                    span: None,
+                    signature: field.field_type.clone(),
+                    content_hash: String::new(),
+                });
+                graph.edge(id, GraphPredicate::Contains, &field_id);
+            }
+
+            for call in &symbol.calls {
+                if let Some(callee) = index.resolve(&scope, symbol, call) {
+                    graph.edge(id, GraphPredicate::Calls, callee);
+                }
+            }
+        }
+    }
+
+    (graph.nodes, graph.edges)
+}
+
+/// 符号的节点 id：stable_id 首次出现时原样使用，之后依次追加 `#2`、`#3`
+fn node_id<'a>(counts: &mut HashMap<&'a str, usize>, stable_id: &'a str) -> String {
+    let count = counts.entry(stable_id).or_insert(0);
+    *count += 1;
+    if *count == 1 {
+        stable_id.to_string()
+    } else {
+        format!("{}#{}", stable_id, count)
+    }
+}
+
+/// 调用解析的作用域：Go 为 (目录, 包名)，其他语言为文件路径
+type Scope = (String, String);
+
+fn scope_of(path: &str, package: Option<&str>) -> Scope {
+    match package {
+        Some(package) => {
+            let dir = path.rsplit_once('/').map_or("", |(dir, _)| dir);
+            (dir.to_string(), package.to_string())
+        }
+        None => (path.to_string(), String::new()),
+    }
+}
+
+/// 作用域内的名字 → 节点 id 索引（同名时保留第一个）
+#[derive(Default)]
+struct ScopeIndex {
+    functions: HashMap<(Scope, String), String>,
+    types: HashMap<(Scope, String), String>,
+    /// (作用域, 类型名, 方法名)
+    methods: HashMap<(Scope, String, String), String>,
+}
+
+impl ScopeIndex {
+    fn insert(&mut self, scope: &Scope, symbol: &Symbol, id: &str) {
+        let id = id.to_string();
+        match (&symbol.kind, &symbol.receiver) {
+            (SymbolKind::Method, Some(receiver)) => {
+                let key = (
+                    scope.clone(),
+                    receiver.type_name.clone(),
+                    symbol.name.clone(),
+                );
+                self.methods.entry(key).or_insert(id);
+            }
+            (SymbolKind::Type | SymbolKind::Interface, _) => {
+                self.types
+                    .entry((scope.clone(), symbol.name.clone()))
+                    .or_insert(id);
+            }
+            // 常量与变量不是调用目标
+            (SymbolKind::Constant | SymbolKind::Variable, _) => {}
+            _ => {
+                self.functions
+                    .entry((scope.clone(), symbol.name.clone()))
+                    .or_insert(id);
+            }
+        }
+    }
+
+    fn resolve(&self, scope: &Scope, caller: &Symbol, call: &CallRef) -> Option<&String> {
+        let Some(receiver_expr) = call.receiver.as_deref() else {
+            return self.functions.get(&(scope.clone(), call.name.clone()));
+        };
+        let receiver = caller.receiver.as_ref()?;
+        let is_self = match receiver.name.as_deref() {
+            Some(name) => receiver_expr == name,
+            None => receiver_expr == "self" || receiver_expr == "this",
+        };
+        if !is_self {
+            return None;
+        }
+        self.methods
+            .get(&(scope.clone(), receiver.type_name.clone(), call.name.clone()))
+    }
+}
+
+#[derive(Default)]
+struct GraphBuilder {
+    nodes: Vec<GraphNode>,
+    edges: Vec<GraphEdge>,
+    node_ids: HashSet<String>,
+    edge_keys: HashSet<GraphEdge>,
+}
+
+impl GraphBuilder {
+    fn node(&mut self, node: GraphNode) {
+        if self.node_ids.insert(node.id.clone()) {
+            self.nodes.push(node);
+        }
+    }
+
+    fn edge(&mut self, subject: &str, predicate: GraphPredicate, object: &str) {
+        let edge = GraphEdge {
+            subject: subject.to_string(),
+            predicate,
+            object: object.to_string(),
+        };
+        if self.edge_keys.insert(edge.clone()) {
+            self.edges.push(edge);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::types::{Field, ParseResult, Receiver};
+
+    fn symbol(
+        name: &str,
+        kind: SymbolKind,
+        receiver: Option<(&str, &str)>,
+        calls: &[(&str, Option<&str>)],
+    ) -> Symbol {
+        Symbol {
+            name: name.to_string(),
+            kind,
+            span: Span {
+                start_line: 1,
+                end_line: 1,
+                start_byte: 0,
+                end_byte: 0,
+            },
+            receiver: receiver.map(|(name, type_name)| Receiver {
+                name: Some(name.to_string()),
+                type_name: type_name.to_string(),
+                is_pointer: true,
+            }),
+            fields: Vec::new(),
+            methods: Vec::new(),
+            docstring: None,
+            signature: String::new(),
+            params: Vec::new(),
+            returns: Vec::new(),
+            type_params: Vec::new(),
+            stable_id: format!("id-{}", name),
+            calls: calls
+                .iter()
+                .map(|(name, receiver)| CallRef {
+                    name: name.to_string(),
+                    receiver: receiver.map(str::to_string),
+                })
+                .collect(),
+            is_exported: true,
+            content_hash: String::new(),
+        }
+    }
+
+    fn file(path: &str, package: Option<&str>, symbols: Vec<Symbol>) -> FileResult {
+        FileResult {
+            path: path.to_string(),
+            result: Some(ParseResult {
+                file_path: path.to_string(),
+                language: "Go".to_string(),
+                entities: Vec::new(),
+                symbols,
+                package: package.map(str::to_string),
+                imports: Vec::new(),
+                exports: Vec::new(),
+                diagnostics: Vec::new(),
+            }),
+            error: None,
+            skipped: None,
+        }
+    }
+
+    fn has(edges: &[GraphEdge], subject: &str, predicate: GraphPredicate, object: &str) -> bool {
+        edges
+            .iter()
+            .any(|e| e.subject == subject && e.predicate == predicate && e.object == object)
+    }
+
+    #[test]
+    fn test_methods_and_calls_resolve_across_files_of_a_package() {
+        let mut service = symbol("Service", SymbolKind::Type, None, &[]);
+        service.fields.push(Field {
+            name: "store".to_string(),
+            field_type: "Store".to_string(),
+            tag: None,
+            is_embedded: false,
+        });
+        let results = [
+            file("pkg/service.go", Some("pkg"), vec![service]),
+            file(
+                "pkg/get.go",
+                Some("pkg"),
+                vec![
+                    symbol(
+                        "Get",
+                        SymbolKind::Method,
+                        Some(("s", "Service")),
+                        &[
+                            ("load", Some("s")),
+                            ("helper", None),
+                            ("Println", Some("fmt")),
+                        ],
+                    ),
+                    symbol("load", SymbolKind::Method, Some(("s", "Service")), &[]),
+                    symbol("helper", SymbolKind::Function, None, &[]),
+                ],
+            ),
+            // 同名函数在其他包中，不应被解析
+            file(
+                "other/helper.go",
+                Some("other"),
+                vec![symbol(
+                    "otherHelper",
+                    SymbolKind::Function,
+                    None,
+                    &[("Get", None)],
+                )],
+            ),
+        ];
+
+        let (nodes, edges) = to_graph(&results);
+
+        assert!(nodes
+            .iter()
+            .any(|n| n.id == "file:pkg/get.go" && n.kind == GraphNodeKind::File));
+        assert!(nodes
+            .iter()
+            .any(|n| n.id == "id-Service#store" && n.kind == GraphNodeKind::Field));
+        assert!(has(
+            &edges,
+            "file:pkg/service.go",
+            GraphPredicate::Defines,
+            "id-Service"
+        ));
+        assert!(has(
+            &edges,
+            "id-Service",
+            GraphPredicate::Contains,
+            "id-Service#store"
+        ));
+        assert!(has(
+            &edges,
+            "id-Service",
+            GraphPredicate::Contains,
+            "id-Get"
+        ));
+        assert!(!has(
+            &edges,
+            "file:pkg/get.go",
+            GraphPredicate::Defines,
+            "id-Get"
+        ));
+        assert!(has(
+            &edges,
+            "file:pkg/get.go",
+            GraphPredicate::Defines,
+            "id-helper"
+        ));
+        assert!(has(&edges, "id-Get", GraphPredicate::Calls, "id-load"));
+        assert!(has(&edges, "id-Get", GraphPredicate::Calls, "id-helper"));
+        assert_eq!(
+            edges
+                .iter()
+                .filter(|e| e.predicate == GraphPredicate::Calls)
+                .count(),
+            2
+        );
+    }
+
+    #[test]
+    fn test_constants_and_variables_are_not_call_targets() {
+        let results = [file(
+            "pkg/values.go",
+            Some("pkg"),
+            vec![
+                symbol("Store", SymbolKind::Interface, None, &[]),
+                symbol("limit", SymbolKind::Constant, None, &[]),
+                symbol(
+                    "defaultStore",
+                    SymbolKind::Variable,
+                    None,
+                    &[("newStore", None)],
+                ),
+                symbol("newStore", SymbolKind::Function, None, &[("limit", None)]),
+            ],
+        )];
+
+        let (nodes, edges) = to_graph(&results);
+        let kind = |id: &str| nodes.iter().find(|n| n.id == id).map(|n| n.kind);
+
+        assert_eq!(kind("id-Store"), Some(GraphNodeKind::Interface));
+        assert_eq!(kind("id-limit"), Some(GraphNodeKind::Constant));
+        assert_eq!(kind("id-defaultStore"), Some(GraphNodeKind::Variable));
+        assert!(has(
+            &edges,
+            "file:pkg/values.go",
+            GraphPredicate::Defines,
+            "id-limit"
+        ));
+        // 变量的初始化表达式可以调用函数，但常量不会被解析为调用目标
+        assert!(has(
+            &edges,
+            "id-defaultStore",
+            GraphPredicate::Calls,
+            "id-newStore"
+        ));
+        assert!(!has(
+            &edges,
+            "id-newStore",
+            GraphPredicate::Calls,
+            "id-limit"
+        ));
+    }
+
+    #[test]
+    fn test_failed_files_are_skipped_and_colliding_ids_are_numbered() {
+        let results = [
+            file(
+                "a.go",
+                Some("main"),
+                vec![symbol(
+                    "init",
+                    SymbolKind::Function,
+                    None,
+                    &[("helper", None)],
+                )],
+            ),
+            FileResult {
+                path: "b.go".to_string(),
+                result: None,
+                error: Some("Failed to read".to_string()),
+                skipped: None,
+            },
+            file(
+                "c.go",
+                Some("main"),
+                vec![
+                    symbol("init", SymbolKind::Function, None, &[]),
+                    symbol("init", SymbolKind::Function, None, &[]),
+                    symbol("helper", SymbolKind::Function, None, &[]),
+                ],
+            ),
+            // 重复输入的同一文件不产生新节点
+            file(
+                "a.go",
+                Some("main"),
+                vec![symbol("init", SymbolKind::Function, None, &[])],
+            ),
+        ];
+
+        let (nodes, edges) = to_graph(&results);
+        let ids: Vec<&str> = nodes.iter().map(|n| n.id.as_str()).collect();
+        assert_eq!(
+            ids,
+            [
+                "file:a.go",
+                "id-init",
+                "file:c.go",
+                "id-init#2",
+                "id-init#3",
+                "id-helper"
+            ]
+        );
+        assert_eq!(nodes[3].file_path, "c.go");
+        assert!(has(
+            &edges,
+            "file:c.go",
+            GraphPredicate::Defines,
+            "id-init#2"
+        ));
+        assert!(has(&edges, "id-init", GraphPredicate::Calls, "id-helper"));
+    }
+
+    #[test]
+    fn test_symbol_moved_within_a_package_keeps_its_node_id() {
+        let before = [
+            file(
+                "pkg/a.go",
+                Some("pkg"),
+                vec![symbol(
+                    "Run",
+                    SymbolKind::Function,
+                    None,
+                    &[("helper", None)],
+                )],
+            ),
+            file(
+                "pkg/b.go",
+                Some("pkg"),
+                vec![symbol("helper", SymbolKind::Function, None, &[])],
+            ),
+        ];
+        let after = [
+            file(
+                "pkg/a.go",
+                Some("pkg"),
+                vec![
+                    symbol("Run", SymbolKind::Function, None, &[("helper", None)]),
+                    symbol("helper", SymbolKind::Function, None, &[]),
+                ],
+            ),
+            file("pkg/b.go", Some("pkg"), Vec::new()),
+        ];
+
+        let (old_nodes, old_edges) = to_graph(&before);
+        let (new_nodes, new_edges) = to_graph(&after);
+        let helper = |nodes: &[GraphNode]| {
+            nodes
+                .iter()
+                .find(|n| n.name == "helper")
+                .map(|n| (n.id.clone(), n.file_path.clone()))
+                .unwrap()
+        };
+        assert_eq!(
+            helper(&old_nodes),
+            ("id-helper".to_string(), "pkg/b.go".to_string())
+        );
+        assert_eq!(
+            helper(&new_nodes),
+            ("id-helper".to_string(), "pkg/a.go".to_string())
+        );
+        assert!(has(
+            &old_edges,
+            "file:pkg/b.go",
+            GraphPredicate::Defines,
+            "id-helper"
+        ));
+        assert!(has(
+            &new_edges,
+            "file:pkg/a.go",
+            GraphPredicate::Defines,
+            "id-helper"
+        ));
+        for edges in [&old_edges, &new_edges] {
+            assert!(has(edges, "id-Run", GraphPredicate::Calls, "id-helper"));
+        }
+    }
+
+    #[test]
+    fn test_same_named_symbols_in_different_packages_are_separate_nodes() {
+        // 真实的 stable_id 包含包目录，这里用目录前缀模拟
+        let scoped = |dir: &str, mut symbols: Vec<Symbol>| {
+            for symbol in &mut symbols {
+                symbol.stable_id = format!("{}/{}", dir, symbol.name);
+            }
+            symbols
+        };
+        let mut user = symbol("User", SymbolKind::Type, None, &[]);
+        user.fields.push(Field {
+            name: "ID".to_string(),
+            field_type: "string".to_string(),
+            tag: None,
+            is_embedded: false,
+        });
+        let results = [
+            file(
+                "a/user.go",
+                Some("models"),
+                scoped(
+                    "a",
+                    vec![
+                        user.clone(),
+                        symbol("Save", SymbolKind::Method, Some(("u", "User")), &[]),
+                        symbol("main", SymbolKind::Function, None, &[("Save", Some("u"))]),
+                    ],
+                ),
+            ),
+            file(
+                "b/user.go",
+                Some("models"),
+                scoped(
+                    "b",
+                    vec![
+                        user,
+                        symbol(
+                            "Save",
+                            SymbolKind::Method,
+                            Some(("u", "User")),
+                            &[("main", None)],
+                        ),
+                        symbol("main", SymbolKind::Function, None, &[]),
+                    ],
+                ),
+            ),
+        ];
+
+        let (nodes, edges) = to_graph(&results);
+        for name in ["User", "Save", "main"] {
+            let ids: Vec<&str> = nodes
+                .iter()
+                .filter(|n| n.name == name)
+                .map(|n| n.id.as_str())
+                .collect();
+            assert_eq!(
+                ids,
+                [format!("a/{}", name), format!("b/{}", name)],
+                "{}",
+                name
+            );
+        }
+
+        // 方法、字段与调用都挂在各自包的节点上
+        assert!(has(&edges, "a/User", GraphPredicate::Contains, "a/Save"));
+        assert!(has(&edges, "b/User", GraphPredicate::Contains, "b/Save"));
+        assert!(has(&edges, "a/User", GraphPredicate::Contains, "a/User#ID"));
+        assert!(has(&edges, "b/User", GraphPredicate::Contains, "b/User#ID"));
+        assert!(has(&edges, "b/Save", GraphPredicate::Calls, "b/main"));
+        assert_eq!(
+            edges
+                .iter()
+                .filter(|e| e.predicate == GraphPredicate::Calls)
+                .count(),
+            1
+        );
+    }
+
+    #[test]
+    fn test_edges_serialize_as_facts() {
+        let edge = GraphEdge {
+            subject: "a".to_string(),
+            predicate: GraphPredicate::Calls,
+            object: "b".to_string(),
+        };
+        assert_eq!(
+            serde_json::to_string(&edge).unwrap(),
+            r#"{"subject":"a","predicate":"CALLS","object":"b"}"#
+        );
+    }
+}
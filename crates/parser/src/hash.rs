@@ -0,0 +1,28 @@
+/// FNV-1a 64 位哈希
+///
+/// 结果跨进程、跨版本稳定（std 的 DefaultHasher 不保证），可以持久化到图数据库
+pub fn fnv1a_64(bytes: &[u8]) -> u64 {
+    const OFFSET_BASIS: u64 = 0xcbf2_9ce4_8422_2325;
+    const PRIME: u64 = 0x0000_0100_0000_01b3;
+
+    bytes.iter().fold(OFFSET_BASIS, |hash, &b| {
+        (hash ^ u64::from(b)).wrapping_mul(PRIME)
+    })
+}
+
+/// 以 16 位十六进制字符串表示哈希（u64 超出 JS Number 的安全整数范围）
+pub fn to_hex(hash: u64) -> String {
+    format!("{:016x}", hash)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_fnv1a_64_known_vectors() {
+        assert_eq!(fnv1a_64(b""), 0xcbf2_9ce4_8422_2325);
+        assert_eq!(fnv1a_64(b"a"), 0xaf63_dc4c_8601_ec8c);
+        assert_eq!(to_hex(fnv1a_64(b"foobar")), "85944171f73967e8");
+    }
+}
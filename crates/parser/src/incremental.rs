@@ -0,0 +1,190 @@
+use std::collections::HashMap;
+
+use serde::{Deserialize, Serialize};
+use tree_sitter::{InputEdit, Point};
+
+use crate::types::{ParseResult, Symbol};
+
+/// 增量解析结果：完整的新解析结果 + 与上次结果的符号差异
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct IncrementalParse {
+    pub result: ParseResult,
+    pub diff: SymbolDiff,
+}
+
+/// 两次解析之间的符号差异（按符号身份匹配，见 `symbol_key`）
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct SymbolDiff {
+    pub added: Vec<Symbol>,
+    pub removed: Vec<Symbol>,
+    /// 源码或文档注释发生变化
+    pub modified: Vec<Symbol>,
+    /// 内容未变、仅位置移动（上方代码增删导致）
+    pub moved: Vec<Symbol>,
+    /// 包声明变化：所有符号的身份都随之变化，旧符号全部 removed、新符号全部 added
+    pub package_changed: bool,
+    pub imports_changed: bool,
+}
+
+impl SymbolDiff {
+    /// 没有任何需要写入的变化
+    pub fn is_empty(&self) -> bool {
+        self.added.is_empty()
+            && self.removed.is_empty()
+            && self.modified.is_empty()
+            && self.moved.is_empty()
+            && !self.package_changed
+            && !self.imports_changed
+    }
+}
+
+/// 符号身份：方法为 `Receiver.Name`，其他符号为 `Name`
+pub fn symbol_key(symbol: &Symbol) -> String {
+    match &symbol.receiver {
+        Some(receiver) => format!("{}.{}", receiver.type_name, symbol.name),
+        None => symbol.name.clone(),
+    }
+}
+
+/// 为符号生成身份键；同名符号（如多个 `init`）按出现顺序追加 `#n`
+fn keyed(symbols: &[Symbol]) -> Vec<(String, &Symbol)> {
+    let mut seen: HashMap<String, usize> = HashMap::new();
+    symbols
+        .iter()
+        .map(|symbol| {
+            let key = symbol_key(symbol);
+            let count = seen.entry(key.clone()).or_insert(0);
+            *count += 1;
+            let key = if *count == 1 { key } else { format!("{}#{}", key, count) };
+            (key, symbol)
+        })
+        .collect()
+}
+
+/// 比较同一文件的两次解析结果
+pub fn diff_results(previous: &ParseResult, current: &ParseResult) -> SymbolDiff {
+    let import_keys = |result: &ParseResult| -> Vec<(String, Option<String>)> {
+        result
+            .imports
+            .iter()
+            .map(|i| (i.source.clone(), i.alias.clone()))
+            .collect()
+    };
+
+    let mut diff = SymbolDiff {
+        package_changed: previous.package != current.package,
+        imports_changed: import_keys(previous) != import_keys(current),
+        ..SymbolDiff::default()
+    };
+
+    if diff.package_changed {
+        diff.added = current.symbols.clone();
+        diff.removed = previous.symbols.clone();
+        return diff;
+    }
+
+    let mut old: HashMap<String, &Symbol> = keyed(&previous.symbols).into_iter().collect();
+
+    for (key, symbol) in keyed(&current.symbols) {
+        match old.remove(&key) {
+            None => diff.added.push(symbol.clone()),
+            Some(prev) if prev.content_hash != symbol.content_hash || prev.docstring != symbol.docstring => {
+                diff.modified.push(symbol.clone())
+            }
+            Some(prev) if prev.span != symbol.span => diff.moved.push(symbol.clone()),
+            Some(_) => {}
+        }
+    }
+
+    // 保持旧文件中的顺序
+    diff.removed = keyed(&previous.symbols)
+        .into_iter()
+        .filter(|(key, _)| old.contains_key(key))
+        .map(|(_, symbol)| symbol.clone())
+        .collect();
+
+    diff
+}
+
+/// 根据新旧源码的公共前缀/后缀计算单个编辑区间，内容相同时返回 None
+pub fn compute_edit(old: &str, new: &str) -> Option<InputEdit> {
+    if old == new {
+        return None;
+    }
+
+    let (old_bytes, new_bytes) = (old.as_bytes(), new.as_bytes());
+    let prefix = old_bytes
+        .iter()
+        .zip(new_bytes)
+        .take_while(|(a, b)| a == b)
+        .count();
+
+    let max_suffix = old_bytes.len().min(new_bytes.len()) - prefix;
+    let suffix = old_bytes
+        .iter()
+        .rev()
+        .zip(new_bytes.iter().rev())
+        .take(max_suffix)
+        .take_while(|(a, b)| a == b)
+        .count();
+
+    let old_end = old_bytes.len() - suffix;
+    let new_end = new_bytes.len() - suffix;
+
+    Some(InputEdit {
+        start_byte: prefix,
+        old_end_byte: old_end,
+        new_end_byte: new_end,
+        start_position: point_at(old_bytes, prefix),
+        old_end_position: point_at(old_bytes, old_end),
+        new_end_position: point_at(new_bytes, new_end),
+    })
+}
+
+/// 字节偏移对应的 tree-sitter 位置（行 0-based，列为行内字节偏移）
+fn point_at(bytes: &[u8], offset: usize) -> Point {
+    let before = &bytes[..offset];
+    let row = before.iter().filter(|&&b| b == b'\n').count();
+    let column = before
+        .iter()
+        .rposition(|&b| b == b'\n')
+        .map_or(offset, |newline| offset - newline - 1);
+    Point::new(row, column)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_compute_edit_identical() {
+        assert!(compute_edit("package main\n", "package main\n").is_none());
+    }
+
+    #[test]
+    fn test_compute_edit_replacement() {
+        let old = "package main\n\nfunc A() { return 1 }\n";
+        let new = "package main\n\nfunc A() { return 42 }\n";
+        let edit = compute_edit(old, new).unwrap();
+
+        assert_eq!(edit.start_byte, 32);
+        assert_eq!(edit.old_end_byte, 33);
+        assert_eq!(edit.new_end_byte, 34);
+        assert_eq!(edit.start_position, Point::new(2, 18));
+        assert_eq!(edit.new_end_position, Point::new(2, 20));
+    }
+
+    #[test]
+    fn test_compute_edit_insert_lines() {
+        let old = "a\nc\n";
+        let new = "a\nb\nc\n";
+        let edit = compute_edit(old, new).unwrap();
+
+        assert_eq!((edit.start_byte, edit.old_end_byte, edit.new_end_byte), (2, 2, 4));
+        assert_eq!(edit.start_position, Point::new(1, 0));
+        assert_eq!(edit.old_end_position, Point::new(1, 0));
+        assert_eq!(edit.new_end_position, Point::new(2, 0));
+    }
+}
@@ -1,24 +1,38 @@
-use tree_sitter::{Language, Parser, Query, QueryCursor};
+use tree_sitter::{Language, Parser, Query, QueryCursor, Tree};
 use std::collections::{HashMap, HashSet};
+use std::io::Read;
 
 use crate::language::SupportedLanguage;
 use crate::strategies::{create_strategy, Capture, ParseStrategy};
+use crate::symbols::{create_extractor, SymbolExtractor};
 use crate::queries::get_query;
-use crate::ext_to_lang::guess_language;
-use crate::types::ParseResult;
+use crate::ext_to_lang::{detect_language, guess_language, DetectedLanguage};
+use crate::options::ParseOptions;
+use crate::incremental::{compute_edit, diff_results, IncrementalParse};
+use crate::types::{ParseResult, Symbol};
+use crate::diagnostics::collect_diagnostics;
 
-/// 语言资源（Parser + Query + Strategy）
+/// 语言资源（Parser + Query + Strategy + SymbolExtractor）
 struct LanguageResources {
     #[allow(dead_code)]
     language: Language,
     parser: Parser,
     query: Query,
     strategy: Box<dyn ParseStrategy>,
+    extractor: Option<Box<dyn SymbolExtractor>>,
+}
+
+/// 增量解析缓存：上次解析的源码与语法树
+struct CachedTree {
+    language: SupportedLanguage,
+    source: String,
+    tree: Tree,
 }
 
 /// 多语言管理器（核心）
 pub struct LanguageManager {
     resources: HashMap<SupportedLanguage, LanguageResources>,
+    trees: HashMap<String, CachedTree>, // 仅 reparse_file 使用
 }
 
 impl LanguageManager {
@@ -26,6 +40,7 @@ impl LanguageManager {
     pub fn new() -> Self {
         Self {
             resources: HashMap::new(),
+            trees: HashMap::new(),
         }
     }
     
@@ -58,11 +73,15 @@ impl LanguageManager {
         // 创建策略
         let strategy = create_strategy(lang);
         
+        // 创建符号提取器（可选）
+        let extractor = create_extractor(lang);
+        
         Ok(LanguageResources {
             language,
             parser,
             query,
             strategy,
+            extractor,
         })
     }
     
@@ -70,15 +89,33 @@ impl LanguageManager {
     pub fn guess_language(&self, file_path: &str) -> Option<SupportedLanguage> {
         guess_language(file_path)
     }
+
+    /// 根据文件路径与内容检测语言（扩展名优先，内容启发式兜底）
+    pub fn detect_language(&self, file_path: &str, source_code: &str) -> DetectedLanguage {
+        detect_language(file_path, source_code.as_bytes())
+    }
     
     /// 解析单个文件
     pub fn parse_file(&mut self, file_path: &str, source_code: &str) -> Result<ParseResult, String> {
-        let lang = self.guess_language(file_path)
+        let lang = self.detect_language(file_path, source_code)
+            .language()
             .ok_or_else(|| format!("Unsupported file type: {}", file_path))?;
         
         self.parse_with_language(file_path, source_code, lang)
     }
     
+    /// 按选项解析单个文件（如只保留导出符号）
+    pub fn parse_file_with_options(
+        &mut self,
+        file_path: &str,
+        source_code: &str,
+        options: &ParseOptions,
+    ) -> Result<ParseResult, String> {
+        let mut result = self.parse_file(file_path, source_code)?;
+        options.apply(&mut result);
+        Ok(result)
+    }
+    
     /// 使用指定语言解析
     pub fn parse_with_language(
         &mut self,
@@ -93,45 +130,98 @@ impl LanguageManager {
             .parse(source_code, None)
             .ok_or("Failed to parse source code")?;
         
-        let root_node = tree.root_node();
+        Ok(build_result(resources, file_path, source_code, lang, &tree))
+    }
+    
+    /// 流式解析：逐个产出符号，而不是构建完整的 ParseResult
+    ///
+    /// tree-sitter 需要完整源码才能建树，reader 的内容会被读入一个缓冲区（跨 chunk 的多字节
+    /// 字符不受影响，span 与 parse_file 一致）；省下的是符号列表与实体片段。emit 返回错误
+    /// （如下游写入需要背压）时立即停止解析并原样返回该错误。file_path 只用于计算 stable_id，
+    /// 传入与 parse_file 相同的路径即可得到相同的 ID
+    pub fn parse_stream<R: Read>(
+        &mut self,
+        file_path: &str,
+        mut reader: R,
+        lang: SupportedLanguage,
+        options: &ParseOptions,
+        mut emit: impl FnMut(Symbol) -> Result<(), String>,
+    ) -> Result<(), String> {
+        let mut bytes = Vec::new();
+        reader
+            .read_to_end(&mut bytes)
+            .map_err(|e| format!("Failed to read source: {}", e))?;
+        let source_code = String::from_utf8(bytes)
+            .map_err(|e| format!("Source is not valid UTF-8: {}", e))?;
         
-        // 使用 query 提取代码实体
-        let mut cursor = QueryCursor::new();
-        let matches = cursor.matches(&resources.query, root_node, source_code.as_bytes());
-        
-        let mut processed_chunks = HashSet::new();
-        let mut entities = Vec::new();
-        
-        for match_ in matches {
-            for capture in match_.captures {
-                let capture_name = resources.query.capture_names()[capture.index as usize];
-                
-                let capture_data = Capture {
-                    node: capture.node,
-                    name: capture_name,
-                };
-                
-                if let Some(code) = resources.strategy.parse_capture(
-                    capture_data,
-                    source_code,
-                    &mut processed_chunks,
-                ) {
-                    entities.push(code);
-                }
-            }
+        let resources = self.load_language(lang)?;
+        if resources.extractor.is_none() {
+            return Err(format!("Symbol extraction is not supported for {}", lang));
         }
         
-        // 构建结果
-        Ok(ParseResult {
-            file_path: file_path.to_string(),
-            language: format!("{}", lang),
-            entities,
-            imports: Vec::new(), // TODO: 单独提取
-            exports: Vec::new(), // TODO: 单独提取
-            errors: Vec::new(),
+        let tree = resources.parser
+            .parse(&source_code, None)
+            .ok_or("Failed to parse source code")?;
+        let root_node = tree.root_node();
+        let extractor = resources.extractor.as_ref().unwrap();
+        
+        let language = format!("{}", lang);
+        let package = extractor.extract_package(root_node, &source_code);
+        
+        extractor.extract_each(root_node, &source_code, &mut |mut symbol| {
+            if !options.keeps(&symbol) {
+                return Ok(());
+            }
+            symbol.stable_id = symbol.compute_stable_id(&language, file_path, package.as_deref());
+            emit(symbol)
         })
     }
     
+    /// 增量重新解析：复用上次该文件的语法树，并返回与 `previous` 相比的符号差异
+    ///
+    /// 首次调用（尚无缓存）时退化为完整解析并建立缓存；不再监听的文件用 `forget_file` 释放
+    pub fn reparse_file(
+        &mut self,
+        previous: &ParseResult,
+        source_code: &str,
+    ) -> Result<IncrementalParse, String> {
+        let file_path = previous.file_path.as_str();
+        let lang = self.detect_language(file_path, source_code)
+            .language()
+            .ok_or_else(|| format!("Unsupported file type: {}", file_path))?;
+        
+        // 语言变化（如扩展名不变但内容启发式结果不同）时缓存的语法树不可复用
+        let old_tree = self.trees
+            .remove(file_path)
+            .filter(|cached| cached.language == lang)
+            .map(|mut cached| {
+                if let Some(edit) = compute_edit(&cached.source, source_code) {
+                    cached.tree.edit(&edit);
+                }
+                cached.tree
+            });
+        
+        let resources = self.load_language(lang)?;
+        let tree = resources.parser
+            .parse(source_code, old_tree.as_ref())
+            .ok_or("Failed to parse source code")?;
+        let result = build_result(resources, file_path, source_code, lang, &tree);
+        
+        self.trees.insert(file_path.to_string(), CachedTree {
+            language: lang,
+            source: source_code.to_string(),
+            tree,
+        });
+        
+        let diff = diff_results(previous, &result);
+        Ok(IncrementalParse { result, diff })
+    }
+    
+    /// 释放文件的增量解析缓存
+    pub fn forget_file(&mut self, file_path: &str) {
+        self.trees.remove(file_path);
+    }
+    
     /// 批量解析文件
     pub fn parse_files_batch(
         &mut self,
@@ -143,7 +233,7 @@ impl LanguageManager {
         let mut by_lang: HashMap<SupportedLanguage, Vec<(String, String)>> = HashMap::new();
         
         for (path, content) in files {
-            if let Some(lang) = self.guess_language(&path) {
+            if let Some(lang) = self.detect_language(&path, &content).language() {
                 by_lang.entry(lang).or_default().push((path, content));
             }
         }
@@ -173,6 +263,70 @@ impl LanguageManager {
     }
 }
 
+/// 从语法树构建解析结果（实体 + 结构化符号）
+fn build_result(
+    resources: &LanguageResources,
+    file_path: &str,
+    source_code: &str,
+    lang: SupportedLanguage,
+    tree: &Tree,
+) -> ParseResult {
+    let root_node = tree.root_node();
+    
+    // 使用 query 提取代码实体
+    let mut cursor = QueryCursor::new();
+    let matches = cursor.matches(&resources.query, root_node, source_code.as_bytes());
+    
+    let mut processed_chunks = HashSet::new();
+    let mut entities = Vec::new();
+    
+    for match_ in matches {
+        for capture in match_.captures {
+            let capture_name = resources.query.capture_names()[capture.index as usize];
+            
+            let capture_data = Capture {
+                node: capture.node,
+                name: capture_name,
+            };
+            
+            if let Some(code) = resources.strategy.parse_capture(
+                capture_data,
+                source_code,
+                &mut processed_chunks,
+            ) {
+                entities.push(code);
+            }
+        }
+    }
+    
+    // 提取结构化符号、包声明与 import（语法错误处 tree-sitter 会恢复，错误之外的声明照常提取）
+    let (mut symbols, package, imports) = match resources.extractor.as_ref() {
+        Some(extractor) => (
+            extractor.extract(root_node, source_code),
+            extractor.extract_package(root_node, source_code),
+            extractor.extract_imports(root_node, source_code, file_path),
+        ),
+        None => (Vec::new(), None, Vec::new()),
+    };
+    
+    let language = format!("{}", lang);
+    for symbol in &mut symbols {
+        symbol.stable_id = symbol.compute_stable_id(&language, file_path, package.as_deref());
+    }
+    
+    // 构建结果
+    ParseResult {
+        file_path: file_path.to_string(),
+        language,
+        entities,
+        symbols,
+        package,
+        imports,
+        exports: Vec::new(), // TODO: 单独提取
+        diagnostics: collect_diagnostics(root_node, source_code),
+    }
+}
+
 /// 加载 tree-sitter 语言
 fn load_tree_sitter_language(lang: SupportedLanguage) -> Result<Language, String> {
     // 统一使用 0.23.x API：所有语言包都提供 LANGUAGE 常量（LanguageFn 类型）
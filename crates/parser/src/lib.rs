@@ -2,8 +2,15 @@ mod types;
 mod language;
 mod ext_to_lang;
 mod strategies;
+mod symbols;
 mod queries;
 mod language_manager;
+mod hash;
+mod incremental;
+mod diagnostics;
+mod graph;
+mod directory;
+mod options;
 
 // 旧版实现（保留）
 mod parser;
@@ -11,7 +18,12 @@ mod extractor;
 
 pub use types::*;
 pub use language::SupportedLanguage;
+pub use ext_to_lang::{detect_language, DetectedLanguage};
 pub use language_manager::LanguageManager;
+pub use incremental::{IncrementalParse, SymbolDiff};
+pub use directory::{parse_dir, FileResult, SkipReason};
+pub use graph::{to_graph, GraphEdge, GraphNode, GraphNodeKind, GraphPredicate};
+pub use options::{is_test_file, ParseOptions, DEFAULT_MAX_FILE_BYTES};
 
 // 旧版 API（保留兼容性）
 pub use parser::ASTParser as LegacyASTParser;
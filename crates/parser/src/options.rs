@@ -0,0 +1,86 @@
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use crate::types::{ParseResult, Symbol};
+
+/// `ParseOptions::max_file_bytes` 的默认值（4 MiB）
+pub const DEFAULT_MAX_FILE_BYTES: u64 = 4 * 1024 * 1024;
+
+/// 解析选项（各解析入口共用，默认不过滤符号）
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase", default)]
+pub struct ParseOptions {
+    /// 只保留导出符号（Go 为首字母大写）；结构体字段列表保持完整
+    pub exported_only: bool,
+    /// 目录解析时跳过测试文件，见 `is_test_file`
+    pub exclude_tests: bool,
+    /// 目录解析时跳过超过该字节数的文件（记录在 `FileResult::skipped`），0 表示不限制
+    pub max_file_bytes: u64,
+}
+
+impl Default for ParseOptions {
+    fn default() -> Self {
+        Self {
+            exported_only: false,
+            exclude_tests: false,
+            max_file_bytes: DEFAULT_MAX_FILE_BYTES,
+        }
+    }
+}
+
+impl ParseOptions {
+    /// 符号是否保留
+    pub fn keeps(&self, symbol: &Symbol) -> bool {
+        !self.exported_only || symbol.is_exported
+    }
+
+    /// 按选项过滤解析结果中的符号
+    pub fn apply(&self, result: &mut ParseResult) {
+        if self.exported_only {
+            result.symbols.retain(|symbol| symbol.is_exported);
+        }
+    }
+}
+
+/// 按各语言的命名约定判断测试文件：
+/// `*_test.go`、`test_*.py` / `*_test.py`、`*.test.*` / `*.spec.*`（JS/TS）
+pub fn is_test_file(file_path: &str) -> bool {
+    let Some(file_name) = Path::new(file_path).file_name().and_then(|n| n.to_str()) else {
+        return false;
+    };
+
+    if file_name.ends_with("_test.go") {
+        return true;
+    }
+    if let Some(stem) = file_name.strip_suffix(".py") {
+        return stem.starts_with("test_") || stem.ends_with("_test");
+    }
+
+    let mut parts = file_name.rsplitn(3, '.');
+    match (parts.next(), parts.next(), parts.next()) {
+        (Some(ext), Some(marker), Some(_)) => {
+            matches!(marker, "test" | "spec") && matches!(ext, "ts" | "tsx" | "js" | "jsx" | "mts" | "mjs")
+        }
+        _ => false,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_is_test_file() {
+        assert!(is_test_file("pkg/user_test.go"));
+        assert!(is_test_file("tests/test_user.py"));
+        assert!(is_test_file("user_test.py"));
+        assert!(is_test_file("src/user.spec.ts"));
+        assert!(is_test_file("src/user.test.jsx"));
+
+        assert!(!is_test_file("pkg/user.go"));
+        assert!(!is_test_file("pkg/testdata.go"));
+        assert!(!is_test_file("src/latest.ts"));
+        assert!(!is_test_file("contest.py"));
+    }
+}
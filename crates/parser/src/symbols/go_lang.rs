@@ -0,0 +1,592 @@
+use tree_sitter::Node;
+
+use super::{content_hash, leading_comments, node_span, normalize_whitespace, SymbolExtractor};
+use crate::strategies::get_node_text;
+use crate::types::{
+    CallRef, Field, ImportDeclaration, InterfaceMethod, Param, Receiver, Symbol, SymbolKind, TypeParam,
+};
+
+/// Go 符号提取器
+pub struct GoSymbolExtractor;
+
+impl GoSymbolExtractor {
+    fn extract_function(&self, node: Node, source_code: &str) -> Option<Symbol> {
+        let name = get_node_text(node.child_by_field_name("name")?, source_code).to_string();
+
+        Some(Symbol {
+            is_exported: is_exported(&name),
+            name,
+            kind: SymbolKind::Function,
+            span: node_span(node),
+            receiver: None,
+            fields: Vec::new(),
+            methods: Vec::new(),
+            docstring: leading_comments(node, source_code),
+            signature: self.signature(node, source_code),
+            params: node
+                .child_by_field_name("parameters")
+                .map(|parameters| self.params(parameters, source_code))
+                .unwrap_or_default(),
+            returns: self.returns(node, source_code),
+            type_params: node
+                .child_by_field_name("type_parameters")
+                .map(|list| self.type_params(list, source_code))
+                .unwrap_or_default(),
+            stable_id: String::new(),
+            calls: node
+                .child_by_field_name("body")
+                .map(|body| self.extract_calls(body, source_code))
+                .unwrap_or_default(),
+            content_hash: content_hash(node, source_code),
+        })
+    }
+
+    /// 函数签名：类型参数 + 参数类型 + 返回值类型，不含参数名，如 `(string)(*User,error)`
+    fn signature(&self, node: Node, source_code: &str) -> String {
+        let mut signature = node
+            .child_by_field_name("type_parameters")
+            .map(|t| normalize_whitespace(get_node_text(t, source_code)))
+            .unwrap_or_default();
+
+        if let Some(parameters) = node.child_by_field_name("parameters") {
+            signature.push_str(&self.parameter_types(parameters, source_code));
+        }
+        if let Some(result) = node.child_by_field_name("result") {
+            if result.kind() == "parameter_list" {
+                signature.push_str(&self.parameter_types(result, source_code));
+            } else {
+                signature.push_str(&normalize_whitespace(get_node_text(result, source_code)));
+            }
+        }
+        signature
+    }
+
+    /// `(a, b int, opts ...Option)` → `(int,int,...Option)`
+    fn parameter_types(&self, parameter_list: Node, source_code: &str) -> String {
+        let types: Vec<String> = self
+            .params(parameter_list, source_code)
+            .into_iter()
+            .map(|p| if p.is_variadic { format!("...{}", p.param_type) } else { p.param_type })
+            .collect();
+
+        format!("({})", types.join(","))
+    }
+
+    /// `(a, b int, opts ...Option)` → a int、b int、opts Option（可变参数）；匿名参数没有名字
+    fn params(&self, parameter_list: Node, source_code: &str) -> Vec<Param> {
+        let mut params = Vec::new();
+
+        let mut cursor = parameter_list.walk();
+        for parameter in parameter_list.named_children(&mut cursor) {
+            let Some(type_node) = parameter.child_by_field_name("type") else {
+                continue;
+            };
+            let param_type = normalize_whitespace(get_node_text(type_node, source_code));
+            let is_variadic = match parameter.kind() {
+                "parameter_declaration" => false,
+                "variadic_parameter_declaration" => true,
+                _ => continue,
+            };
+
+            let mut name_cursor = parameter.walk();
+            let names: Vec<String> = parameter
+                .children_by_field_name("name", &mut name_cursor)
+                .map(|n| get_node_text(n, source_code).to_string())
+                .collect();
+            if names.is_empty() {
+                params.push(Param { name: None, param_type, is_variadic });
+                continue;
+            }
+            for name in names {
+                params.push(Param {
+                    name: Some(name),
+                    param_type: param_type.clone(),
+                    is_variadic,
+                });
+            }
+        }
+
+        params
+    }
+
+    /// `[K comparable, V any]` → K comparable、V any；`[T, U any]` 中共享约束的名字展开为多个参数
+    fn type_params(&self, type_parameter_list: Node, source_code: &str) -> Vec<TypeParam> {
+        let mut type_params = Vec::new();
+
+        let mut cursor = type_parameter_list.walk();
+        for declaration in type_parameter_list.named_children(&mut cursor) {
+            if declaration.kind() != "type_parameter_declaration" {
+                continue;
+            }
+            let constraint = declaration
+                .child_by_field_name("type")
+                .map(|t| normalize_whitespace(get_node_text(t, source_code)))
+                .unwrap_or_default();
+
+            let mut name_cursor = declaration.walk();
+            for name in declaration.children_by_field_name("name", &mut name_cursor) {
+                type_params.push(TypeParam {
+                    name: get_node_text(name, source_code).to_string(),
+                    constraint: constraint.clone(),
+                });
+            }
+        }
+
+        type_params
+    }
+
+    /// 返回值类型：`error` → [error]，`(*User, error)` / `(created bool, err error)` → 只取类型
+    fn returns(&self, node: Node, source_code: &str) -> Vec<String> {
+        let Some(result) = node.child_by_field_name("result") else {
+            return Vec::new();
+        };
+        if result.kind() == "parameter_list" {
+            return self
+                .params(result, source_code)
+                .into_iter()
+                .map(|p| p.param_type)
+                .collect();
+        }
+        vec![normalize_whitespace(get_node_text(result, source_code))]
+    }
+
+    /// 类型签名：结构体/接口只取关键字（字段变化不改变身份），其他取规范化的底层类型；别名以 `=` 开头
+    fn type_signature(&self, spec: Node, type_node: Node, source_code: &str) -> String {
+        let underlying = match type_node.kind() {
+            "struct_type" => "struct".to_string(),
+            "interface_type" => "interface".to_string(),
+            _ => normalize_whitespace(get_node_text(type_node, source_code)),
+        };
+        let type_parameters = spec
+            .child_by_field_name("type_parameters")
+            .map(|t| normalize_whitespace(get_node_text(t, source_code)))
+            .unwrap_or_default();
+
+        if spec.kind() == "type_alias" {
+            format!("{}={}", type_parameters, underlying)
+        } else {
+            format!("{}{}", type_parameters, underlying)
+        }
+    }
+
+    /// 收集函数体内所有调用表达式（含闭包内的调用）
+    fn extract_calls(&self, body: Node, source_code: &str) -> Vec<CallRef> {
+        let mut calls = Vec::new();
+        self.collect_calls(body, source_code, &mut calls);
+        calls
+    }
+
+    fn collect_calls(&self, node: Node, source_code: &str, calls: &mut Vec<CallRef>) {
+        if node.kind() == "call_expression" {
+            if let Some(call) = node
+                .child_by_field_name("function")
+                .and_then(|function| self.call_ref(function, source_code))
+            {
+                if !calls.contains(&call) {
+                    calls.push(call);
+                }
+            }
+        }
+
+        let mut cursor = node.walk();
+        for child in node.named_children(&mut cursor) {
+            self.collect_calls(child, source_code, calls);
+        }
+    }
+
+    /// `f()` → f；`pkg.F()` / `s.mu.Lock()` → 接收者为点号之前的表达式；闭包立即调用等形式忽略
+    fn call_ref(&self, function: Node, source_code: &str) -> Option<CallRef> {
+        match function.kind() {
+            "identifier" => Some(CallRef {
+                name: get_node_text(function, source_code).to_string(),
+                receiver: None,
+            }),
+            "selector_expression" => Some(CallRef {
+                name: get_node_text(function.child_by_field_name("field")?, source_code).to_string(),
+                receiver: function
+                    .child_by_field_name("operand")
+                    .map(|operand| get_node_text(operand, source_code).to_string()),
+            }),
+            // 显式实例化的泛型函数 `Map[int](xs)`
+            "index_expression" | "generic_type" => function
+                .named_child(0)
+                .and_then(|callee| self.call_ref(callee, source_code)),
+            "parenthesized_expression" => function
+                .named_child(0)
+                .and_then(|inner| self.call_ref(inner, source_code)),
+            _ => None,
+        }
+    }
+
+    fn extract_method(&self, node: Node, source_code: &str) -> Option<Symbol> {
+        let mut symbol = self.extract_function(node, source_code)?;
+        symbol.kind = SymbolKind::Method;
+        symbol.receiver = node
+            .child_by_field_name("receiver")
+            .and_then(|receiver| self.extract_receiver(receiver, source_code));
+        Some(symbol)
+    }
+
+    /// 解析接收者：`(s *UserService)`、`(UserService)`、`(s *Stack[T])`
+    fn extract_receiver(&self, parameter_list: Node, source_code: &str) -> Option<Receiver> {
+        let mut cursor = parameter_list.walk();
+        let parameter = parameter_list
+            .named_children(&mut cursor)
+            .find(|n| n.kind() == "parameter_declaration")?;
+
+        let type_node = parameter.child_by_field_name("type")?;
+        let type_name = get_node_text(type_node, source_code)
+            .trim_start_matches('*')
+            .split('[')
+            .next()?
+            .trim()
+            .to_string();
+
+        Some(Receiver {
+            name: parameter
+                .child_by_field_name("name")
+                .map(|n| get_node_text(n, source_code).to_string()),
+            type_name,
+            is_pointer: type_node.kind() == "pointer_type",
+        })
+    }
+
+    fn extract_types(&self, node: Node, source_code: &str, symbols: &mut Vec<Symbol>) {
+        // 单个 `type X ...` 的注释挂在 type_declaration 上，分组 `type ( ... )` 的注释挂在各 spec 上
+        let declaration_doc = leading_comments(node, source_code);
+
+        let mut cursor = node.walk();
+        for spec in node.named_children(&mut cursor) {
+            if spec.kind() != "type_spec" && spec.kind() != "type_alias" {
+                continue;
+            }
+            if let Some(name_node) = spec.child_by_field_name("name") {
+                let name = get_node_text(name_node, source_code).to_string();
+                let type_node = spec.child_by_field_name("type");
+                let (kind, fields, methods) = match type_node {
+                    Some(t) if t.kind() == "struct_type" => {
+                        (SymbolKind::Type, self.extract_fields(t, source_code), Vec::new())
+                    }
+                    Some(t) if t.kind() == "interface_type" => {
+                        let (embedded, methods) = self.extract_interface(t, source_code);
+                        (SymbolKind::Interface, embedded, methods)
+                    }
+                    _ => (SymbolKind::Type, Vec::new(), Vec::new()),
+                };
+
+                symbols.push(Symbol {
+                    is_exported: is_exported(&name),
+                    name,
+                    kind,
+                    span: node_span(spec),
+                    receiver: None,
+                    fields,
+                    methods,
+                    docstring: leading_comments(spec, source_code).or_else(|| declaration_doc.clone()),
+                    signature: spec
+                        .child_by_field_name("type")
+                        .map(|t| self.type_signature(spec, t, source_code))
+                        .unwrap_or_default(),
+                    params: Vec::new(),
+                    returns: Vec::new(),
+                    type_params: spec
+                        .child_by_field_name("type_parameters")
+                        .map(|list| self.type_params(list, source_code))
+                        .unwrap_or_default(),
+                    stable_id: String::new(),
+                    calls: Vec::new(),
+                    content_hash: content_hash(spec, source_code),
+                });
+            }
+        }
+    }
+
+    /// 提取结构体字段；`X, Y int` 展开为两个字段，嵌入字段以类型名作为字段名
+    fn extract_fields(&self, struct_type: Node, source_code: &str) -> Vec<Field> {
+        let mut fields = Vec::new();
+
+        let mut cursor = struct_type.walk();
+        let list = struct_type
+            .named_children(&mut cursor)
+            .find(|n| n.kind() == "field_declaration_list");
+        let Some(list) = list else {
+            return fields;
+        };
+
+        let mut list_cursor = list.walk();
+        for declaration in list.named_children(&mut list_cursor) {
+            if declaration.kind() != "field_declaration" {
+                continue;
+            }
+            let Some(type_node) = declaration.child_by_field_name("type") else {
+                continue;
+            };
+            let field_type = get_node_text(type_node, source_code).to_string();
+            let tag = declaration.child_by_field_name("tag").map(|t| {
+                get_node_text(t, source_code)
+                    .trim_matches(|c| c == '`' || c == '"')
+                    .to_string()
+            });
+
+            let mut name_cursor = declaration.walk();
+            let names: Vec<String> = declaration
+                .children_by_field_name("name", &mut name_cursor)
+                .map(|n| get_node_text(n, source_code).to_string())
+                .collect();
+
+            if names.is_empty() {
+                // 嵌入字段：`*pkg.Type` 的字段名为 `Type`，`*` 是 type 之外的匿名节点
+                let name = embedded_name(&field_type);
+                let mut star_cursor = declaration.walk();
+                let is_pointer = declaration
+                    .children(&mut star_cursor)
+                    .any(|c| c.kind() == "*");
+                fields.push(Field {
+                    name,
+                    field_type: if is_pointer { format!("*{}", field_type) } else { field_type },
+                    tag,
+                    is_embedded: true,
+                });
+                continue;
+            }
+
+            for name in names {
+                fields.push(Field {
+                    name,
+                    field_type: field_type.clone(),
+                    tag: tag.clone(),
+                    is_embedded: false,
+                });
+            }
+        }
+
+        fields
+    }
+
+    /// 提取接口的方法集与嵌入的接口（以嵌入字段表示）；`~int | ~float64` 这类类型约束不产生条目
+    fn extract_interface(&self, interface_type: Node, source_code: &str) -> (Vec<Field>, Vec<InterfaceMethod>) {
+        let mut embedded = Vec::new();
+        let mut methods = Vec::new();
+
+        let mut cursor = interface_type.walk();
+        for element in interface_type.named_children(&mut cursor) {
+            match element.kind() {
+                "method_elem" => {
+                    let Some(name) = element.child_by_field_name("name") else {
+                        continue;
+                    };
+                    methods.push(InterfaceMethod {
+                        name: get_node_text(name, source_code).to_string(),
+                        signature: self.signature(element, source_code),
+                        params: element
+                            .child_by_field_name("parameters")
+                            .map(|parameters| self.params(parameters, source_code))
+                            .unwrap_or_default(),
+                        returns: self.returns(element, source_code),
+                    });
+                }
+                // 嵌入接口是只有一个类型的 type_elem；联合或 `~T` 是约束
+                "type_elem" if element.named_child_count() == 1 => {
+                    let Some(type_node) = element.named_child(0).filter(|t| t.kind() != "negated_type") else {
+                        continue;
+                    };
+                    let field_type = normalize_whitespace(get_node_text(type_node, source_code));
+                    embedded.push(Field {
+                        name: embedded_name(&field_type),
+                        field_type,
+                        tag: None,
+                        is_embedded: true,
+                    });
+                }
+                _ => {}
+            }
+        }
+
+        (embedded, methods)
+    }
+
+    /// 提取 `const`/`var` 声明的每个名字；单个声明的注释挂在 declaration 上，分组形式的挂在各 spec 上
+    fn extract_values(&self, node: Node, kind: SymbolKind, source_code: &str, symbols: &mut Vec<Symbol>) {
+        let declaration_doc = leading_comments(node, source_code);
+
+        let mut specs = Vec::new();
+        let mut cursor = node.walk();
+        for child in node.named_children(&mut cursor) {
+            match child.kind() {
+                "const_spec" | "var_spec" => specs.push(child),
+                "var_spec_list" => {
+                    let mut list_cursor = child.walk();
+                    specs.extend(child.named_children(&mut list_cursor).filter(|n| n.kind() == "var_spec"));
+                }
+                _ => {}
+            }
+        }
+
+        // 分组常量省略类型与值时沿用上一行（`iota` 枚举）
+        let mut previous_type = String::new();
+        for spec in specs {
+            let value = spec.child_by_field_name("value");
+            let signature = match spec.child_by_field_name("type") {
+                Some(t) => normalize_whitespace(get_node_text(t, source_code)),
+                None if kind == SymbolKind::Constant && value.is_none() => previous_type.clone(),
+                None => String::new(),
+            };
+            previous_type = signature.clone();
+            let calls = value
+                .map(|v| self.extract_calls(v, source_code))
+                .unwrap_or_default();
+            let docstring = leading_comments(spec, source_code).or_else(|| declaration_doc.clone());
+
+            let mut name_cursor = spec.walk();
+            for name_node in spec.children_by_field_name("name", &mut name_cursor) {
+                let name = get_node_text(name_node, source_code).to_string();
+                if name == "_" {
+                    continue;
+                }
+                symbols.push(Symbol {
+                    is_exported: is_exported(&name),
+                    name,
+                    kind,
+                    span: node_span(spec),
+                    receiver: None,
+                    fields: Vec::new(),
+                    methods: Vec::new(),
+                    docstring: docstring.clone(),
+                    signature: signature.clone(),
+                    params: Vec::new(),
+                    returns: Vec::new(),
+                    type_params: Vec::new(),
+                    stable_id: String::new(),
+                    calls: calls.clone(),
+                    content_hash: content_hash(spec, source_code),
+                });
+            }
+        }
+    }
+
+    /// 解析单个 import_spec：`"fmt"`、`f "fmt"`、`. "fmt"`、`_ "embed"`
+    fn extract_import_spec(&self, spec: Node, source_code: &str, file_path: &str) -> Option<ImportDeclaration> {
+        let path = spec.child_by_field_name("path")?;
+        let source = get_node_text(path, source_code)
+            .trim_matches(|c| c == '"' || c == '`')
+            .to_string();
+
+        Some(ImportDeclaration {
+            source,
+            specifiers: Vec::new(),
+            file_path: file_path.to_string(),
+            is_type_only: false,
+            alias: spec
+                .child_by_field_name("name")
+                .map(|n| get_node_text(n, source_code).to_string()),
+        })
+    }
+
+    /// 提取单个顶层声明的符号
+    fn extract_declaration(&self, node: Node, source_code: &str, symbols: &mut Vec<Symbol>) {
+        match node.kind() {
+            "function_declaration" => symbols.extend(self.extract_function(node, source_code)),
+            "method_declaration" => symbols.extend(self.extract_method(node, source_code)),
+            "type_declaration" => self.extract_types(node, source_code, symbols),
+            "const_declaration" => self.extract_values(node, SymbolKind::Constant, source_code, symbols),
+            "var_declaration" => self.extract_values(node, SymbolKind::Variable, source_code, symbols),
+            // 语法错误恢复时，tree-sitter 可能把错误附近的完整声明一并包进 ERROR 节点
+            "ERROR" => {
+                let mut cursor = node.walk();
+                for child in node.named_children(&mut cursor) {
+                    self.extract_declaration(child, source_code, symbols);
+                }
+            }
+            _ => {}
+        }
+    }
+}
+
+impl SymbolExtractor for GoSymbolExtractor {
+    fn extract_each(
+        &self,
+        root: Node,
+        source_code: &str,
+        emit: &mut dyn FnMut(Symbol) -> Result<(), String>,
+    ) -> Result<(), String> {
+        let mut cursor = root.walk();
+        for node in root.named_children(&mut cursor) {
+            // 每次只缓冲一个顶层声明的符号（分组 type 声明可能产生多个）
+            let mut symbols = Vec::new();
+            self.extract_declaration(node, source_code, &mut symbols);
+
+            for symbol in symbols {
+                emit(symbol)?;
+            }
+        }
+
+        Ok(())
+    }
+
+    fn extract_package(&self, root: Node, source_code: &str) -> Option<String> {
+        let mut cursor = root.walk();
+        let clause = root
+            .named_children(&mut cursor)
+            .find(|n| n.kind() == "package_clause")?;
+
+        let mut clause_cursor = clause.walk();
+        let name = clause
+            .named_children(&mut clause_cursor)
+            .find(|n| n.kind() == "package_identifier")?;
+        Some(get_node_text(name, source_code).to_string())
+    }
+
+    fn extract_imports(&self, root: Node, source_code: &str, file_path: &str) -> Vec<ImportDeclaration> {
+        let mut imports = Vec::new();
+
+        let mut cursor = root.walk();
+        for declaration in root.named_children(&mut cursor) {
+            if declaration.kind() != "import_declaration" {
+                continue;
+            }
+
+            // `import "fmt"` 直接包含 import_spec，分组形式包含 import_spec_list
+            let mut specs = Vec::new();
+            let mut decl_cursor = declaration.walk();
+            for child in declaration.named_children(&mut decl_cursor) {
+                match child.kind() {
+                    "import_spec" => specs.push(child),
+                    "import_spec_list" => {
+                        let mut list_cursor = child.walk();
+                        specs.extend(
+                            child
+                                .named_children(&mut list_cursor)
+                                .filter(|n| n.kind() == "import_spec"),
+                        );
+                    }
+                    _ => {}
+                }
+            }
+
+            imports.extend(
+                specs
+                    .into_iter()
+                    .filter_map(|spec| self.extract_import_spec(spec, source_code, file_path)),
+            );
+        }
+
+        imports
+    }
+}
+
+/// 嵌入字段的字段名：`*pkg.Type[T]` → `Type`
+fn embedded_name(field_type: &str) -> String {
+    field_type
+        .rsplit('.')
+        .next()
+        .unwrap_or(field_type)
+        .split('[')
+        .next()
+        .unwrap_or(field_type)
+        .to_string()
+}
+
+/// Go 的导出规则：首字母大写
+fn is_exported(name: &str) -> bool {
+    name.chars().next().map_or(false, |c| c.is_uppercase())
+}
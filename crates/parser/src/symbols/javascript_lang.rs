@@ -0,0 +1,336 @@
+use tree_sitter::Node;
+
+use super::{content_hash, leading_comments, node_span, normalize_whitespace, SymbolExtractor};
+use crate::strategies::get_node_text;
+use crate::types::{CallRef, Field, Param, Receiver, Symbol, SymbolKind};
+
+/// JavaScript 符号提取器（JavaScript 使用 TSX 语法解析，节点名称以 tree-sitter-typescript 为准）
+///
+/// 类映射为 `SymbolKind::Type`，类方法为 `Method`；`const f = () => {}` 形式的函数表达式视为函数。
+/// 只有 `export` 语句中的声明标记为导出
+pub struct JavaScriptSymbolExtractor;
+
+impl JavaScriptSymbolExtractor {
+    /// `node` 为函数声明或函数表达式；`outer` 为承载注释与 span 的语句（export / 变量声明）
+    fn extract_function(
+        &self,
+        name: String,
+        node: Node,
+        outer: Node,
+        source_code: &str,
+        is_exported: bool,
+    ) -> Symbol {
+        Symbol {
+            is_exported,
+            name,
+            kind: SymbolKind::Function,
+            span: node_span(outer),
+            receiver: None,
+            fields: Vec::new(),
+            methods: Vec::new(),
+            docstring: leading_comments(outer, source_code),
+            signature: self.signature(node, source_code),
+            params: self.params(node, source_code),
+            returns: node
+                .child_by_field_name("return_type")
+                .map(|t| vec![type_annotation(t, source_code)])
+                .unwrap_or_default(),
+            type_params: Vec::new(),
+            stable_id: String::new(),
+            calls: node
+                .child_by_field_name("body")
+                .map(|body| self.extract_calls(body, source_code))
+                .unwrap_or_default(),
+            content_hash: content_hash(outer, source_code),
+        }
+    }
+
+    /// 函数签名：规范化的参数列表（保留参数名）+ 返回值注解，如 `(id)`、`(user,{notify=true})`
+    fn signature(&self, node: Node, source_code: &str) -> String {
+        let mut signature = match node.child_by_field_name("parameters") {
+            Some(parameters) => normalize_whitespace(get_node_text(parameters, source_code)),
+            // 单参数箭头函数 `x => x * 2`
+            None => node
+                .child_by_field_name("parameter")
+                .map(|p| format!("({})", get_node_text(p, source_code)))
+                .unwrap_or_else(|| "()".to_string()),
+        };
+        if let Some(return_type) = node.child_by_field_name("return_type") {
+            signature.push_str(&normalize_whitespace(get_node_text(return_type, source_code)));
+        }
+        signature
+    }
+
+    /// `(id, offset = 0, ...rest)`；`...rest` 记为可变参数，解构参数以规范化后的模式作为名字
+    fn params(&self, node: Node, source_code: &str) -> Vec<Param> {
+        let Some(parameters) = node.child_by_field_name("parameters") else {
+            // 单参数箭头函数 `x => x * 2`
+            return node
+                .child_by_field_name("parameter")
+                .map(|p| {
+                    vec![Param {
+                        name: Some(get_node_text(p, source_code).to_string()),
+                        param_type: String::new(),
+                        is_variadic: false,
+                    }]
+                })
+                .unwrap_or_default();
+        };
+
+        let mut params = Vec::new();
+        let mut cursor = parameters.walk();
+        for parameter in parameters.named_children(&mut cursor) {
+            if parameter.kind() != "required_parameter" && parameter.kind() != "optional_parameter" {
+                continue;
+            }
+            let Some(pattern) = parameter.child_by_field_name("pattern") else {
+                continue;
+            };
+            let is_variadic = pattern.kind() == "rest_pattern";
+            let name = normalize_whitespace(get_node_text(pattern, source_code));
+
+            params.push(Param {
+                name: Some(name.trim_start_matches("...").to_string()),
+                param_type: parameter
+                    .child_by_field_name("type")
+                    .map(|t| type_annotation(t, source_code))
+                    .unwrap_or_default(),
+                is_variadic,
+            });
+        }
+
+        params
+    }
+
+    /// 收集函数体内所有调用表达式（含回调与嵌套函数内的调用）
+    fn extract_calls(&self, body: Node, source_code: &str) -> Vec<CallRef> {
+        let mut calls = Vec::new();
+        self.collect_calls(body, source_code, &mut calls);
+        calls
+    }
+
+    fn collect_calls(&self, node: Node, source_code: &str, calls: &mut Vec<CallRef>) {
+        if node.kind() == "call_expression" {
+            if let Some(call) = node
+                .child_by_field_name("function")
+                .and_then(|function| self.call_ref(function, source_code))
+            {
+                if !calls.contains(&call) {
+                    calls.push(call);
+                }
+            }
+        }
+
+        let mut cursor = node.walk();
+        for child in node.named_children(&mut cursor) {
+            self.collect_calls(child, source_code, calls);
+        }
+    }
+
+    /// `f()` → f；`this.users.get()` / `EMAIL_RE.test()` → 接收者为点号之前的表达式
+    fn call_ref(&self, function: Node, source_code: &str) -> Option<CallRef> {
+        match function.kind() {
+            "identifier" => Some(CallRef {
+                name: get_node_text(function, source_code).to_string(),
+                receiver: None,
+            }),
+            "member_expression" => Some(CallRef {
+                name: get_node_text(function.child_by_field_name("property")?, source_code).to_string(),
+                receiver: function
+                    .child_by_field_name("object")
+                    .map(|object| get_node_text(object, source_code).to_string()),
+            }),
+            "parenthesized_expression" => function
+                .named_child(0)
+                .and_then(|inner| self.call_ref(inner, source_code)),
+            _ => None,
+        }
+    }
+
+    /// 类本身产出一个 Type 符号，类体内的方法各产出一个 Method 符号
+    fn extract_class(
+        &self,
+        node: Node,
+        outer: Node,
+        source_code: &str,
+        is_exported: bool,
+        symbols: &mut Vec<Symbol>,
+    ) {
+        let Some(name_node) = node.child_by_field_name("name") else {
+            return;
+        };
+        let name = get_node_text(name_node, source_code).to_string();
+        let body = node.child_by_field_name("body");
+
+        symbols.push(Symbol {
+            is_exported,
+            name: name.clone(),
+            kind: SymbolKind::Type,
+            span: node_span(outer),
+            receiver: None,
+            fields: body
+                .map(|b| self.extract_fields(b, source_code))
+                .unwrap_or_default(),
+            methods: Vec::new(),
+            docstring: leading_comments(outer, source_code),
+            // 与 Go 的 `struct` 一致：成员变化不改变类型身份
+            signature: "class".to_string(),
+            params: Vec::new(),
+            returns: Vec::new(),
+            type_params: Vec::new(),
+            stable_id: String::new(),
+            calls: Vec::new(),
+            content_hash: content_hash(outer, source_code),
+        });
+
+        let Some(body) = body else {
+            return;
+        };
+        let mut cursor = body.walk();
+        for member in body.named_children(&mut cursor) {
+            if member.kind() != "method_definition" {
+                continue;
+            }
+            let Some(method_name) = member.child_by_field_name("name") else {
+                continue;
+            };
+            let method_name = get_node_text(method_name, source_code).to_string();
+            // `#private` 方法在类外不可访问
+            let method_exported = is_exported && !method_name.starts_with('#');
+            let mut method = self.extract_function(method_name, member, member, source_code, method_exported);
+            method.kind = SymbolKind::Method;
+            method.receiver = Some(Receiver {
+                name: None,
+                type_name: name.clone(),
+                is_pointer: false,
+            });
+            symbols.push(method);
+        }
+    }
+
+    /// 类字段声明（`users = new Map()`、`#secret`），字段类型取自 TS 注解，JS 中为空
+    fn extract_fields(&self, body: Node, source_code: &str) -> Vec<Field> {
+        let mut fields = Vec::new();
+
+        let mut cursor = body.walk();
+        for member in body.named_children(&mut cursor) {
+            if member.kind() != "public_field_definition" && member.kind() != "field_definition" {
+                continue;
+            }
+            let Some(name_node) = member
+                .child_by_field_name("name")
+                .or_else(|| member.child_by_field_name("property"))
+            else {
+                continue;
+            };
+            fields.push(Field {
+                name: get_node_text(name_node, source_code).to_string(),
+                field_type: member
+                    .child_by_field_name("type")
+                    .map(|t| type_annotation(t, source_code))
+                    .unwrap_or_default(),
+                tag: None,
+                is_embedded: false,
+            });
+        }
+
+        fields
+    }
+
+    /// 提取单个顶层语句的符号；`outer` 为 export 语句（若有）
+    fn extract_statement(
+        &self,
+        node: Node,
+        outer: Node,
+        source_code: &str,
+        is_exported: bool,
+        symbols: &mut Vec<Symbol>,
+    ) {
+        match node.kind() {
+            "function_declaration" | "generator_function_declaration" => {
+                if let Some(name) = node.child_by_field_name("name") {
+                    let name = get_node_text(name, source_code).to_string();
+                    symbols.push(self.extract_function(name, node, outer, source_code, is_exported));
+                }
+            }
+            "class_declaration" => self.extract_class(node, outer, source_code, is_exported, symbols),
+            // `const getUser = async (id) => {...}`
+            "lexical_declaration" | "variable_declaration" => {
+                let mut cursor = node.walk();
+                for declarator in node.named_children(&mut cursor) {
+                    if declarator.kind() != "variable_declarator" {
+                        continue;
+                    }
+                    let (Some(name), Some(value)) = (
+                        declarator.child_by_field_name("name"),
+                        declarator.child_by_field_name("value"),
+                    ) else {
+                        continue;
+                    };
+                    if !is_function_expression(value) || name.kind() != "identifier" {
+                        continue;
+                    }
+                    let name = get_node_text(name, source_code).to_string();
+                    symbols.push(self.extract_function(name, value, outer, source_code, is_exported));
+                }
+            }
+            _ => {}
+        }
+    }
+
+    /// 提取单个顶层语句（含 export 语句）的符号
+    fn extract_top_level(&self, node: Node, source_code: &str, symbols: &mut Vec<Symbol>) {
+        match node.kind() {
+            "export_statement" => {
+                if let Some(declaration) = node
+                    .child_by_field_name("declaration")
+                    .or_else(|| node.child_by_field_name("value"))
+                {
+                    self.extract_statement(declaration, node, source_code, true, symbols);
+                }
+            }
+            // 语法错误恢复时，tree-sitter 可能把错误附近的完整语句一并包进 ERROR 节点
+            "ERROR" => {
+                let mut cursor = node.walk();
+                for child in node.named_children(&mut cursor) {
+                    self.extract_top_level(child, source_code, symbols);
+                }
+            }
+            _ => self.extract_statement(node, node, source_code, false, symbols),
+        }
+    }
+}
+
+impl SymbolExtractor for JavaScriptSymbolExtractor {
+    fn extract_each(
+        &self,
+        root: Node,
+        source_code: &str,
+        emit: &mut dyn FnMut(Symbol) -> Result<(), String>,
+    ) -> Result<(), String> {
+        let mut cursor = root.walk();
+        for node in root.named_children(&mut cursor) {
+            // 每次只缓冲一个顶层语句的符号（类会连同方法一起产出）
+            let mut symbols = Vec::new();
+            self.extract_top_level(node, source_code, &mut symbols);
+
+            for symbol in symbols {
+                emit(symbol)?;
+            }
+        }
+
+        Ok(())
+    }
+}
+
+/// TS 类型注解 `: Promise<User>` → `Promise<User>`
+fn type_annotation(node: Node, source_code: &str) -> String {
+    normalize_whitespace(get_node_text(node, source_code).trim_start_matches(':'))
+}
+
+fn is_function_expression(node: Node) -> bool {
+    matches!(
+        node.kind(),
+        "arrow_function" | "function_expression" | "function" | "generator_function"
+    )
+}
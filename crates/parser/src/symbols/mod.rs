@@ -0,0 +1,166 @@
+use tree_sitter::Node;
+
+#[cfg(feature = "go")]
+mod go_lang;
+#[cfg(feature = "python")]
+mod python_lang;
+mod javascript_lang;
+
+#[cfg(feature = "go")]
+pub use go_lang::GoSymbolExtractor;
+#[cfg(feature = "python")]
+pub use python_lang::PythonSymbolExtractor;
+pub use javascript_lang::JavaScriptSymbolExtractor;
+
+use crate::language::SupportedLanguage;
+use crate::hash::{fnv1a_64, to_hex};
+use crate::strategies::get_node_text;
+use crate::types::{ImportDeclaration, Span, Symbol};
+
+/// 结构化符号提取 trait
+///
+/// 与 ParseStrategy 并行：策略产出代码片段（entities），提取器产出结构化符号（symbols）
+pub trait SymbolExtractor: Send + Sync {
+    /// 按声明顺序逐个提取符号并交给 emit；emit 返回错误时立即停止并返回该错误
+    fn extract_each(
+        &self,
+        root: Node,
+        source_code: &str,
+        emit: &mut dyn FnMut(Symbol) -> Result<(), String>,
+    ) -> Result<(), String>;
+
+    /// 从语法树根节点提取全部符号
+    fn extract(&self, root: Node, source_code: &str) -> Vec<Symbol> {
+        let mut symbols = Vec::new();
+        // 收集到 Vec 的 emit 不会失败
+        let _ = self.extract_each(root, source_code, &mut |symbol| {
+            symbols.push(symbol);
+            Ok(())
+        });
+        symbols
+    }
+
+    /// 提取包声明（默认无）
+    fn extract_package(&self, _root: Node, _source_code: &str) -> Option<String> {
+        None
+    }
+
+    /// 提取文件的 import 声明（默认不提取）
+    fn extract_imports(&self, _root: Node, _source_code: &str, _file_path: &str) -> Vec<ImportDeclaration> {
+        Vec::new()
+    }
+}
+
+/// 创建语言对应的符号提取器（工厂模式），尚未实现的语言返回 None
+pub fn create_extractor(lang: SupportedLanguage) -> Option<Box<dyn SymbolExtractor>> {
+    match lang {
+        #[cfg(feature = "go")]
+        SupportedLanguage::Go => Some(Box::new(GoSymbolExtractor)),
+        #[cfg(feature = "python")]
+        SupportedLanguage::Python => Some(Box::new(PythonSymbolExtractor)),
+        SupportedLanguage::JavaScript => Some(Box::new(JavaScriptSymbolExtractor)),
+        _ => None,
+    }
+}
+
+/// 辅助函数：提取紧邻节点之前的注释块作为文档
+///
+/// 注释与声明之间有空行、或注释是上一行代码的行尾注释时不关联
+pub fn leading_comments(node: Node, source_code: &str) -> Option<String> {
+    let mut blocks = Vec::new();
+    let mut next_start_row = node.start_position().row;
+    let mut current = node.prev_sibling();
+
+    while let Some(sibling) = current {
+        if !sibling.kind().contains("comment") || sibling.end_position().row + 1 < next_start_row {
+            break;
+        }
+        // 行尾注释：同一行注释之前还有代码。按源码判断而不是比较上一个兄弟节点的行号，
+        // 因为 Go 的换行终止符是独立节点，其结束位置已在下一行
+        let line_start = source_code[..sibling.start_byte()].rfind('\n').map_or(0, |i| i + 1);
+        if !source_code[line_start..sibling.start_byte()].trim().is_empty() {
+            break;
+        }
+        let start_row = sibling.start_position().row;
+
+        blocks.push(clean_comment(get_node_text(sibling, source_code)));
+        next_start_row = start_row;
+        current = sibling.prev_sibling();
+    }
+
+    if blocks.is_empty() {
+        return None;
+    }
+    blocks.reverse();
+    Some(blocks.join("\n"))
+}
+
+/// 去掉注释分隔符（`//`、`#`、`/* */`）
+fn clean_comment(text: &str) -> String {
+    let text = text.trim();
+    if let Some(body) = text.strip_prefix("/*").and_then(|t| t.strip_suffix("*/")) {
+        return body
+            .lines()
+            .map(|line| line.trim().trim_start_matches('*').trim())
+            .collect::<Vec<_>>()
+            .join("\n")
+            .trim()
+            .to_string();
+    }
+
+    let body = text
+        .strip_prefix("//")
+        .or_else(|| text.strip_prefix('#'))
+        .unwrap_or(text);
+    body.strip_prefix(' ').unwrap_or(body).trim_end().to_string()
+}
+
+/// 辅助函数：去掉空白，仅在两个标识符字符之间保留一个空格
+///
+/// `( *User,  error )` → `(*User,error)`，`map[string] []int` → `map[string][]int`
+pub fn normalize_whitespace(text: &str) -> String {
+    let is_word = |c: char| c.is_alphanumeric() || c == '_';
+
+    let mut normalized = String::with_capacity(text.len());
+    let mut pending_space = false;
+    for c in text.chars() {
+        if c.is_whitespace() {
+            pending_space = true;
+            continue;
+        }
+        if pending_space && normalized.chars().last().map_or(false, is_word) && is_word(c) {
+            normalized.push(' ');
+        }
+        pending_space = false;
+        normalized.push(c);
+    }
+    normalized
+}
+
+/// 辅助函数：节点源码的内容哈希
+pub fn content_hash(node: Node, source_code: &str) -> String {
+    to_hex(fnv1a_64(get_node_text(node, source_code).as_bytes()))
+}
+
+/// 辅助函数：获取节点的位置（行号 1-based，与旧版 extractor 一致；字节偏移取自 tree-sitter）
+pub fn node_span(node: Node) -> Span {
+    Span {
+        start_line: node.start_position().row + 1,
+        end_line: node.end_position().row + 1,
+        start_byte: node.start_byte(),
+        end_byte: node.end_byte(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_normalize_whitespace() {
+        assert_eq!(normalize_whitespace("( *User,  error )"), "(*User,error)");
+        assert_eq!(normalize_whitespace("map[string] []int"), "map[string][]int");
+        assert_eq!(normalize_whitespace("<-chan   int"), "<-chan int");
+        assert_eq!(normalize_whitespace("func(\n\tChangeEvent,\n)"), "func(ChangeEvent,)");
+    }
+}
@@ -0,0 +1,326 @@
+use tree_sitter::Node;
+
+use super::{content_hash, leading_comments, node_span, normalize_whitespace, SymbolExtractor};
+use crate::strategies::get_node_text;
+use crate::types::{CallRef, Field, Param, Receiver, Symbol, SymbolKind};
+
+/// Python 符号提取器
+///
+/// 类映射为 `SymbolKind::Type`（与 Go 的结构体一致），类体内的函数为 `Method`，接收者类型为类名
+pub struct PythonSymbolExtractor;
+
+impl PythonSymbolExtractor {
+    /// `node` 为 function_definition；`outer` 为带装饰器时的 decorated_definition，决定 span 与注释位置
+    fn extract_function(&self, node: Node, outer: Node, source_code: &str) -> Option<Symbol> {
+        let name = get_node_text(node.child_by_field_name("name")?, source_code).to_string();
+        let body = node.child_by_field_name("body");
+
+        Some(Symbol {
+            is_exported: is_exported(&name),
+            name,
+            kind: SymbolKind::Function,
+            span: node_span(outer),
+            receiver: None,
+            fields: Vec::new(),
+            methods: Vec::new(),
+            docstring: body
+                .and_then(|b| self.docstring(b, source_code))
+                .or_else(|| leading_comments(outer, source_code)),
+            signature: self.signature(node, source_code),
+            params: node
+                .child_by_field_name("parameters")
+                .map(|parameters| self.params(parameters, source_code))
+                .unwrap_or_default(),
+            returns: node
+                .child_by_field_name("return_type")
+                .map(|t| vec![normalize_whitespace(get_node_text(t, source_code))])
+                .unwrap_or_default(),
+            type_params: Vec::new(),
+            stable_id: String::new(),
+            calls: body
+                .map(|b| self.extract_calls(b, source_code))
+                .unwrap_or_default(),
+            content_hash: content_hash(outer, source_code),
+        })
+    }
+
+    /// 函数签名：规范化的参数列表 + 返回值注解，如 `(self,user_id:str)->Optional[User]`
+    ///
+    /// Python 没有强制的参数类型，且参数名可作为关键字参数使用，因此签名保留参数名
+    fn signature(&self, node: Node, source_code: &str) -> String {
+        let mut signature = node
+            .child_by_field_name("parameters")
+            .map(|p| normalize_whitespace(get_node_text(p, source_code)))
+            .unwrap_or_default();
+        if let Some(return_type) = node.child_by_field_name("return_type") {
+            signature.push_str("->");
+            signature.push_str(&normalize_whitespace(get_node_text(return_type, source_code)));
+        }
+        signature
+    }
+
+    /// `(self, user_id: str, *args, limit=10, **kwargs)`；`*args`/`**kwargs` 记为可变参数，
+    /// 单独的 `*`、`/` 分隔符不是参数
+    fn params(&self, parameters: Node, source_code: &str) -> Vec<Param> {
+        let mut params = Vec::new();
+
+        let mut cursor = parameters.walk();
+        for parameter in parameters.named_children(&mut cursor) {
+            let (name_node, type_node) = match parameter.kind() {
+                "identifier" | "list_splat_pattern" | "dictionary_splat_pattern" => (Some(parameter), None),
+                "default_parameter" => (parameter.child_by_field_name("name"), None),
+                "typed_default_parameter" => (
+                    parameter.child_by_field_name("name"),
+                    parameter.child_by_field_name("type"),
+                ),
+                // typed_parameter 的名字是不带字段名的第一个子节点（可能是 `*args`）
+                "typed_parameter" => (parameter.named_child(0), parameter.child_by_field_name("type")),
+                _ => continue,
+            };
+            let Some(name_node) = name_node else {
+                continue;
+            };
+            let name = get_node_text(name_node, source_code);
+
+            params.push(Param {
+                name: Some(name.trim_start_matches('*').to_string()),
+                param_type: type_node
+                    .map(|t| normalize_whitespace(get_node_text(t, source_code)))
+                    .unwrap_or_default(),
+                is_variadic: name.starts_with('*'),
+            });
+        }
+
+        params
+    }
+
+    /// 函数/类体的第一条语句为字符串字面量时作为文档字符串
+    fn docstring(&self, body: Node, source_code: &str) -> Option<String> {
+        let statement = body.named_child(0)?;
+        if statement.kind() != "expression_statement" {
+            return None;
+        }
+        let string = statement.named_child(0).filter(|n| n.kind() == "string")?;
+        Some(clean_docstring(get_node_text(string, source_code)))
+    }
+
+    /// 收集函数体内所有调用表达式（含嵌套函数与 lambda 内的调用）
+    fn extract_calls(&self, body: Node, source_code: &str) -> Vec<CallRef> {
+        let mut calls = Vec::new();
+        self.collect_calls(body, source_code, &mut calls);
+        calls
+    }
+
+    fn collect_calls(&self, node: Node, source_code: &str, calls: &mut Vec<CallRef>) {
+        if node.kind() == "call" {
+            if let Some(call) = node
+                .child_by_field_name("function")
+                .and_then(|function| self.call_ref(function, source_code))
+            {
+                if !calls.contains(&call) {
+                    calls.push(call);
+                }
+            }
+        }
+
+        let mut cursor = node.walk();
+        for child in node.named_children(&mut cursor) {
+            self.collect_calls(child, source_code, calls);
+        }
+    }
+
+    /// `f()` → f；`self.users.get()` / `re.match()` → 接收者为点号之前的表达式
+    fn call_ref(&self, function: Node, source_code: &str) -> Option<CallRef> {
+        match function.kind() {
+            "identifier" => Some(CallRef {
+                name: get_node_text(function, source_code).to_string(),
+                receiver: None,
+            }),
+            "attribute" => Some(CallRef {
+                name: get_node_text(function.child_by_field_name("attribute")?, source_code).to_string(),
+                receiver: function
+                    .child_by_field_name("object")
+                    .map(|object| get_node_text(object, source_code).to_string()),
+            }),
+            "parenthesized_expression" => function
+                .named_child(0)
+                .and_then(|inner| self.call_ref(inner, source_code)),
+            _ => None,
+        }
+    }
+
+    /// 类本身产出一个 Type 符号，类体内的函数各产出一个 Method 符号
+    fn extract_class(&self, node: Node, outer: Node, source_code: &str, symbols: &mut Vec<Symbol>) {
+        let Some(name_node) = node.child_by_field_name("name") else {
+            return;
+        };
+        let name = get_node_text(name_node, source_code).to_string();
+        let body = node.child_by_field_name("body");
+
+        symbols.push(Symbol {
+            is_exported: is_exported(&name),
+            name: name.clone(),
+            kind: SymbolKind::Type,
+            span: node_span(outer),
+            receiver: None,
+            fields: body
+                .map(|b| self.extract_fields(b, source_code))
+                .unwrap_or_default(),
+            methods: Vec::new(),
+            docstring: body
+                .and_then(|b| self.docstring(b, source_code))
+                .or_else(|| leading_comments(outer, source_code)),
+            // 与 Go 的 `struct` 一致：成员变化不改变类型身份
+            signature: "class".to_string(),
+            params: Vec::new(),
+            returns: Vec::new(),
+            type_params: Vec::new(),
+            stable_id: String::new(),
+            calls: Vec::new(),
+            content_hash: content_hash(outer, source_code),
+        });
+
+        let Some(body) = body else {
+            return;
+        };
+        let mut cursor = body.walk();
+        for child in body.named_children(&mut cursor) {
+            let Some(function) = unwrap_decorated(child).filter(|n| n.kind() == "function_definition") else {
+                continue;
+            };
+            if let Some(mut method) = self.extract_function(function, child, source_code) {
+                method.kind = SymbolKind::Method;
+                method.receiver = Some(Receiver {
+                    name: None,
+                    type_name: name.clone(),
+                    is_pointer: false,
+                });
+                symbols.push(method);
+            }
+        }
+    }
+
+    /// 提取单个顶层定义的符号
+    fn extract_definition(&self, node: Node, source_code: &str, symbols: &mut Vec<Symbol>) {
+        match unwrap_decorated(node) {
+            Some(inner) if inner.kind() == "function_definition" => {
+                symbols.extend(self.extract_function(inner, node, source_code))
+            }
+            Some(inner) if inner.kind() == "class_definition" => {
+                self.extract_class(inner, node, source_code, symbols)
+            }
+            // 语法错误恢复时，tree-sitter 可能把错误附近的完整定义一并包进 ERROR 节点
+            Some(inner) if inner.kind() == "ERROR" => {
+                let mut cursor = inner.walk();
+                for child in inner.named_children(&mut cursor) {
+                    self.extract_definition(child, source_code, symbols);
+                }
+            }
+            _ => {}
+        }
+    }
+
+    /// 类体内带注解的类属性（dataclass 风格的 `id: str`、`count: int = 0`）
+    fn extract_fields(&self, body: Node, source_code: &str) -> Vec<Field> {
+        let mut fields = Vec::new();
+
+        let mut cursor = body.walk();
+        for statement in body.named_children(&mut cursor) {
+            if statement.kind() != "expression_statement" {
+                continue;
+            }
+            let Some(assignment) = statement.named_child(0).filter(|n| n.kind() == "assignment") else {
+                continue;
+            };
+            let (Some(left), Some(type_node)) = (
+                assignment.child_by_field_name("left"),
+                assignment.child_by_field_name("type"),
+            ) else {
+                continue;
+            };
+            if left.kind() != "identifier" {
+                continue;
+            }
+            fields.push(Field {
+                name: get_node_text(left, source_code).to_string(),
+                field_type: get_node_text(type_node, source_code).to_string(),
+                tag: None,
+                is_embedded: false,
+            });
+        }
+
+        fields
+    }
+}
+
+impl SymbolExtractor for PythonSymbolExtractor {
+    fn extract_each(
+        &self,
+        root: Node,
+        source_code: &str,
+        emit: &mut dyn FnMut(Symbol) -> Result<(), String>,
+    ) -> Result<(), String> {
+        let mut cursor = root.walk();
+        for node in root.named_children(&mut cursor) {
+            // 每次只缓冲一个顶层定义的符号（类会连同方法一起产出）
+            let mut symbols = Vec::new();
+            self.extract_definition(node, source_code, &mut symbols);
+
+            for symbol in symbols {
+                emit(symbol)?;
+            }
+        }
+
+        Ok(())
+    }
+}
+
+/// `@decorator def f(): ...` → 内部的 function_definition / class_definition
+fn unwrap_decorated(node: Node) -> Option<Node> {
+    if node.kind() == "decorated_definition" {
+        node.child_by_field_name("definition")
+    } else {
+        Some(node)
+    }
+}
+
+/// 去掉字符串前缀与引号，并去除每行的公共缩进
+fn clean_docstring(text: &str) -> String {
+    let text = text.trim_start_matches(|c: char| c.is_ascii_alphabetic());
+    let body = ["\"\"\"", "'''", "\"", "'"]
+        .iter()
+        .find_map(|quote| text.strip_prefix(quote).and_then(|t| t.strip_suffix(quote)))
+        .unwrap_or(text);
+
+    body.lines()
+        .map(str::trim)
+        .collect::<Vec<_>>()
+        .join("\n")
+        .trim()
+        .to_string()
+}
+
+/// Python 的约定：下划线开头为私有，`__dunder__` 方法除外
+fn is_exported(name: &str) -> bool {
+    !name.starts_with('_') || (name.len() > 4 && name.starts_with("__") && name.ends_with("__"))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_clean_docstring() {
+        assert_eq!(clean_docstring(r#""""获取用户信息""""#), "获取用户信息");
+        assert_eq!(clean_docstring("r'''\n    第一行\n    第二行\n    '''"), "第一行\n第二行");
+        assert_eq!(clean_docstring("'single'"), "single");
+    }
+
+    #[test]
+    fn test_is_exported() {
+        assert!(is_exported("get_user"));
+        assert!(is_exported("__init__"));
+        assert!(!is_exported("_shard_for"));
+        assert!(!is_exported("__private"));
+    }
+}
@@ -1,5 +1,7 @@
 use serde::{Deserialize, Serialize};
 
+use crate::hash::{fnv1a_64, to_hex};
+
 /// 代码实体的统一枚举类型
 #[derive(Debug, Clone, Serialize, Deserialize)]
 #[serde(tag = "kind", rename_all = "lowercase")]
@@ -115,6 +117,19 @@ pub struct Range {
     pub end: usize,
 }
 
+/// 符号在源码中的精确位置
+///
+/// 行号 1-based（含首尾），字节偏移为 UTF-8 源码中的半开区间 `[start_byte, end_byte)`，
+/// 可直接用于 `&source[start_byte..end_byte]` 截取符号原文
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct Span {
+    pub start_line: usize,
+    pub end_line: usize,
+    pub start_byte: usize,
+    pub end_byte: usize,
+}
+
 /// 可见性修饰符
 #[derive(Debug, Clone, Serialize, Deserialize)]
 #[serde(rename_all = "lowercase")]
@@ -131,6 +146,9 @@ pub struct ImportDeclaration {
     pub specifiers: Vec<String>,
     pub file_path: String,
     pub is_type_only: bool,
+    /// 导入别名（Go 的 `alias "path"`、`.`、`_`）
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub alias: Option<String>,
 }
 
 /// Export 声明
@@ -148,6 +166,169 @@ pub struct ParseError {
     pub range: Option<Range>,
 }
 
+/// 语法诊断：tree-sitter 在错误处恢复后报告的问题，span 与符号的 span 含义一致
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct Diagnostic {
+    pub message: String,
+    pub span: Span,
+}
+
+/// 结构化符号种类（跨语言统一）
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum SymbolKind {
+    Function,
+    Method,
+    Type,
+    /// 接口（Go 的 `type X interface {...}`），方法集见 `Symbol::methods`
+    Interface,
+    /// 包级常量（Go 的 `const`）
+    Constant,
+    /// 包级变量（Go 的 `var`）
+    Variable,
+}
+
+/// 方法接收者（Go 的 `(s *UserService)`）
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct Receiver {
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub name: Option<String>,
+    pub type_name: String,
+    pub is_pointer: bool,
+}
+
+/// 结构体字段（按声明顺序）；接口嵌入的其他接口同样以嵌入字段表示
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct Field {
+    pub name: String,
+    pub field_type: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub tag: Option<String>,
+    pub is_embedded: bool,
+}
+
+/// 函数体内的调用点（语法层面，不做类型解析）
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct CallRef {
+    /// 被调用的函数/方法名，如 `MatchString`
+    pub name: String,
+    /// 调用的接收者或包表达式，如 `regexp`、`s.mu`；直接调用时为 None
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub receiver: Option<String>,
+}
+
+/// 函数参数（按声明顺序，`a, b int` 展开为两个参数）
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct Param {
+    /// 参数名；Go 的匿名参数（`func(int)`）为 None
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub name: Option<String>,
+    /// 规范化的参数类型，可变参数记录元素类型（`...Option` 为 `Option`）；没有类型注解时为空
+    pub param_type: String,
+    pub is_variadic: bool,
+}
+
+/// 接口方法集中的一个方法，签名格式与函数相同
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct InterfaceMethod {
+    pub name: String,
+    pub signature: String,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub params: Vec<Param>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub returns: Vec<String>,
+}
+
+/// 泛型类型参数（按声明顺序，`[K, V any]` 展开为两个参数）
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct TypeParam {
+    pub name: String,
+    /// 规范化的约束，如 `any`、`comparable`、`~int|~float64`
+    pub constraint: String,
+}
+
+/// 结构化符号（由 SymbolExtractor 从语法树提取）
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct Symbol {
+    pub name: String,
+    pub kind: SymbolKind,
+    pub span: Span,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub receiver: Option<Receiver>,
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub fields: Vec<Field>,
+    /// 接口声明的方法（按声明顺序，不含嵌入接口的方法）
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub methods: Vec<InterfaceMethod>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub docstring: Option<String>,
+    /// 规范化签名：函数为参数/返回值类型（不含参数名与空白），类型为底层类型，常量/变量为声明的类型（未声明时为空）
+    #[serde(default)]
+    pub signature: String,
+    /// 函数/方法的参数
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub params: Vec<Param>,
+    /// 函数/方法的返回值类型（Go 的具名返回值只保留类型）
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub returns: Vec<String>,
+    /// 泛型函数/类型的类型参数（目前仅 Go）
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub type_params: Vec<TypeParam>,
+    /// 内容寻址的稳定 ID，见 `Symbol::compute_stable_id`
+    #[serde(default)]
+    pub stable_id: String,
+    /// 函数体内的调用（按首次出现顺序去重）
+    #[serde(skip_serializing_if = "Vec::is_empty", default)]
+    pub calls: Vec<CallRef>,
+    pub is_exported: bool,
+    /// 符号源码的 FNV-1a 哈希（十六进制），用于增量解析判断内容是否变化
+    #[serde(default)]
+    pub content_hash: String,
+}
+
+/// 稳定 ID 方案的版本号，改变哈希输入格式时递增
+const STABLE_ID_VERSION: &str = "v2";
+
+impl Symbol {
+    /// 计算稳定 ID，用于图数据库中的幂等 upsert
+    ///
+    /// 方案：FNV-1a 64 位哈希以下字段（`\x1f` 分隔）并输出 16 位十六进制：
+    /// `v2, language, scope, package, receiver 类型名, name, signature`。
+    /// scope 区分同名符号所在的模块：有包声明的语言（Go）为文件所在目录，即包目录；
+    /// 其他语言（Python、JavaScript）为文件路径本身。`file_path` 应为相对仓库根的路径
+    /// （`\` 按 `/` 处理），否则 ID 会随检出位置变化。
+    /// 不包含行号、字节偏移与函数体，因此移动代码、调整空白或修改实现都不会改变 ID；
+    /// 重命名、修改参数/返回值类型、更换接收者、包或所在目录（文件）会得到新 ID
+    pub fn compute_stable_id(&self, language: &str, file_path: &str, package: Option<&str>) -> String {
+        let receiver = self.receiver.as_ref().map_or("", |r| r.type_name.as_str());
+        let path = file_path.replace('\\', "/");
+        let scope = match package {
+            Some(_) => path.rsplit_once('/').map_or("", |(dir, _)| dir),
+            None => path.as_str(),
+        };
+        let canonical = [
+            STABLE_ID_VERSION,
+            language,
+            scope,
+            package.unwrap_or(""),
+            receiver,
+            &self.name,
+            &self.signature,
+        ]
+        .join("\x1f");
+
+        to_hex(fnv1a_64(canonical.as_bytes()))
+    }
+}
+
 /// 解析结果（新版本 - 支持多语言）
 #[derive(Debug, Clone, Serialize, Deserialize)]
 #[serde(rename_all = "camelCase")]
@@ -155,9 +336,15 @@ pub struct ParseResult {
     pub file_path: String,
     pub language: String,
     pub entities: Vec<String>, // 提取的代码片段
+    #[serde(default)]
+    pub symbols: Vec<Symbol>, // 结构化符号（仅已实现 SymbolExtractor 的语言）
+    #[serde(skip_serializing_if = "Option::is_none", default)]
+    pub package: Option<String>, // 包声明（如 Go 的 `package main`）
     pub imports: Vec<ImportDeclaration>,
     pub exports: Vec<ExportDeclaration>,
-    pub errors: Vec<ParseError>,
+    /// 语法错误不会中断解析：其余可识别的声明照常出现在 symbols 中
+    #[serde(default)]
+    pub diagnostics: Vec<Diagnostic>,
 }
 
 /// 旧版解析结果（保留兼容性）
@@ -0,0 +1,63 @@
+use synapse_parser::{LanguageManager, SymbolKind};
+
+#[cfg(feature = "go")]
+const SAMPLE_GO: &str = include_str!("../../../tests/fixtures/multi-language/sample.go");
+#[cfg(feature = "go")]
+const BROKEN_GO: &str = include_str!("../../../tests/fixtures/broken/broken.go");
+
+#[cfg(feature = "go")]
+#[test]
+fn test_valid_source_has_no_diagnostics() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+
+    assert!(result.diagnostics.is_empty(), "{:?}", result.diagnostics);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_broken_go_yields_partial_symbols_and_diagnostics() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("broken.go", BROKEN_GO).unwrap();
+
+    assert_eq!(result.package.as_deref(), Some("broken"));
+    for (name, kind) in [
+        ("Greeter", SymbolKind::Type),
+        ("Greet", SymbolKind::Method),
+        ("Shout", SymbolKind::Function),
+        ("Loud", SymbolKind::Interface),
+    ] {
+        let symbol = result.symbols.iter().find(|s| s.name == name).expect(name);
+        assert_eq!(symbol.kind, kind, "{}", name);
+    }
+
+    // 错误之后的声明 span 仍然准确
+    let shout = result.symbols.iter().find(|s| s.name == "Shout").unwrap();
+    assert!(BROKEN_GO[shout.span.start_byte..shout.span.end_byte].starts_with("func Shout("));
+
+    assert!(!result.diagnostics.is_empty());
+    let broken_line = BROKEN_GO.lines().position(|l| l.starts_with("func Broken")).unwrap() + 1;
+    let shout_line = shout.span.start_line;
+    for diagnostic in &result.diagnostics {
+        assert!(!diagnostic.message.is_empty());
+        assert!(diagnostic.span.start_byte <= diagnostic.span.end_byte);
+        assert!(diagnostic.span.end_byte <= BROKEN_GO.len());
+        assert!(
+            (broken_line..shout_line).contains(&diagnostic.span.start_line),
+            "diagnostic outside the broken region: {:?}",
+            diagnostic
+        );
+    }
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_diagnostics_are_serialized() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("broken.go", BROKEN_GO).unwrap();
+
+    let json = serde_json::to_value(&result).unwrap();
+    let first = &json["diagnostics"][0];
+    assert!(first["message"].is_string());
+    assert!(first["span"]["startLine"].is_u64());
+}
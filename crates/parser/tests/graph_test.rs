@@ -0,0 +1,95 @@
+#![cfg(feature = "go")]
+
+use synapse_parser::{
+    to_graph, FileResult, GraphEdge, GraphNode, GraphNodeKind, GraphPredicate, LanguageManager,
+};
+
+const SAMPLE_GO: &str = include_str!("../../../tests/fixtures/multi-language/sample.go");
+
+/// 与 sample.go 同包的第二个文件，调用另一个文件中的方法与函数
+const LOOKUP_GO: &str = "package main
+
+func (s *UserService) FindUser(email string) (*User, error) {
+\tif !ValidateEmail(email) {
+\t\treturn nil, nil
+\t}
+\treturn s.GetUser(email)
+}
+";
+
+fn sample_graph() -> (Vec<GraphNode>, Vec<GraphEdge>) {
+    let mut manager = LanguageManager::new();
+    let files: Vec<FileResult> = [("sample.go", SAMPLE_GO), ("lookup.go", LOOKUP_GO)]
+        .into_iter()
+        .map(|(path, source)| FileResult {
+            path: path.to_string(),
+            result: Some(manager.parse_file(path, source).unwrap()),
+            error: None,
+            skipped: None,
+        })
+        .collect();
+    to_graph(&files)
+}
+
+fn id_of(nodes: &[GraphNode], kind: GraphNodeKind, name: &str) -> String {
+    nodes
+        .iter()
+        .find(|n| n.kind == kind && n.name == name)
+        .unwrap_or_else(|| panic!("missing {:?} {}", kind, name))
+        .id
+        .clone()
+}
+
+#[test]
+fn test_sample_go_graph_contains_methods_and_fields() {
+    let (nodes, edges) = sample_graph();
+    let has = |subject: &str, predicate: GraphPredicate, object: &str| {
+        edges
+            .iter()
+            .any(|e| e.subject == subject && e.predicate == predicate && e.object == object)
+    };
+
+    let service = id_of(&nodes, GraphNodeKind::Type, "UserService");
+    let get_user = id_of(&nodes, GraphNodeKind::Method, "GetUser");
+    assert!(has(&service, GraphPredicate::Contains, &get_user));
+    assert!(has("file:sample.go", GraphPredicate::Defines, &service));
+    assert!(!has("file:sample.go", GraphPredicate::Defines, &get_user));
+
+    let user = id_of(&nodes, GraphNodeKind::Type, "User");
+    for field in ["ID", "Name", "Email"] {
+        assert!(has(&user, GraphPredicate::Contains, &format!("{}#{}", user, field)), "{}", field);
+    }
+
+    let validate = id_of(&nodes, GraphNodeKind::Function, "ValidateEmail");
+    assert!(has("file:sample.go", GraphPredicate::Defines, &validate));
+}
+
+#[test]
+fn test_sample_go_graph_resolves_calls() {
+    let (nodes, edges) = sample_graph();
+    let has = |subject: &str, predicate: GraphPredicate, object: &str| {
+        edges
+            .iter()
+            .any(|e| e.subject == subject && e.predicate == predicate && e.object == object)
+    };
+
+    // s.GetUser(...) 通过接收者解析为同一类型在另一个文件中的方法
+    let find_user = id_of(&nodes, GraphNodeKind::Method, "FindUser");
+    let get_user = id_of(&nodes, GraphNodeKind::Method, "GetUser");
+    assert!(has(&find_user, GraphPredicate::Calls, &get_user));
+
+    // 包内跨文件的直接调用
+    let validate = id_of(&nodes, GraphNodeKind::Function, "ValidateEmail");
+    assert!(has(&find_user, GraphPredicate::Calls, &validate));
+
+    // 方法挂在另一个文件中定义的类型下
+    let service = id_of(&nodes, GraphNodeKind::Type, "UserService");
+    assert!(has(&service, GraphPredicate::Contains, &find_user));
+
+    // 标准库调用（regexp.MatchString、s.mu.RLock）不产生边，所有边的两端都是已知节点
+    assert!(!edges.iter().any(|e| e.subject == validate && e.predicate == GraphPredicate::Calls));
+    for edge in &edges {
+        assert!(nodes.iter().any(|n| n.id == edge.subject), "{:?}", edge);
+        assert!(nodes.iter().any(|n| n.id == edge.object), "{:?}", edge);
+    }
+}
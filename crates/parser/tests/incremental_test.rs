@@ -0,0 +1,105 @@
+#![cfg(feature = "go")]
+
+use synapse_parser::{LanguageManager, ParseResult};
+
+const SAMPLE_GO: &str = include_str!("../../../tests/fixtures/multi-language/sample.go");
+
+fn names(symbols: &[synapse_parser::Symbol]) -> Vec<&str> {
+    symbols.iter().map(|s| s.name.as_str()).collect()
+}
+
+fn parse(manager: &mut LanguageManager, source: &str) -> ParseResult {
+    manager.parse_file("sample.go", source).unwrap()
+}
+
+#[test]
+fn test_unchanged_content_has_empty_diff() {
+    let mut manager = LanguageManager::new();
+    let previous = parse(&mut manager, SAMPLE_GO);
+
+    let first = manager.reparse_file(&previous, SAMPLE_GO).unwrap();
+    assert!(first.diff.is_empty(), "cold reparse: {:?}", first.diff);
+
+    // 第二次命中语法树缓存，结果应与完整解析一致
+    let second = manager.reparse_file(&first.result, SAMPLE_GO).unwrap();
+    assert!(second.diff.is_empty());
+    assert_eq!(names(&second.result.symbols), names(&previous.symbols));
+}
+
+#[test]
+fn test_modified_function_body() {
+    let mut manager = LanguageManager::new();
+    let previous = parse(&mut manager, SAMPLE_GO);
+    let warm = manager.reparse_file(&previous, SAMPLE_GO).unwrap();
+
+    let changed = SAMPLE_GO.replacen(
+        "errors.New(\"user not found\")",
+        "errors.New(\"no such user\")",
+        1,
+    );
+    let update = manager.reparse_file(&warm.result, &changed).unwrap();
+
+    assert_eq!(names(&update.diff.modified), ["GetUser"]);
+    assert!(update.diff.added.is_empty());
+    assert!(update.diff.removed.is_empty());
+    assert!(!update.diff.package_changed);
+    assert!(!update.diff.imports_changed);
+
+    // 同一行内缩短，后续符号只有字节偏移变化
+    assert!(update.diff.moved.iter().all(|s| s.span.start_byte > update.diff.modified[0].span.start_byte));
+}
+
+#[test]
+fn test_added_and_removed_methods_keyed_by_receiver() {
+    let mut manager = LanguageManager::new();
+    let previous = parse(&mut manager, SAMPLE_GO);
+
+    let changed = SAMPLE_GO.replacen(
+        "func (s *UserService) DeleteUser(id string) bool {",
+        "func (s *UserService) RemoveUser(id string) bool {",
+        1,
+    ) + "\nfunc (u *User) DeleteUser() bool {\n\treturn false\n}\n";
+    let update = manager.reparse_file(&previous, &changed).unwrap();
+
+    // User.DeleteUser 与已删除的 UserService.DeleteUser 是不同的符号
+    let mut added: Vec<String> = update
+        .diff
+        .added
+        .iter()
+        .map(|s| format!("{}.{}", s.receiver.as_ref().unwrap().type_name, s.name))
+        .collect();
+    added.sort();
+    assert_eq!(added, ["User.DeleteUser", "UserService.RemoveUser"]);
+    assert_eq!(names(&update.diff.removed), ["DeleteUser"]);
+    assert_eq!(update.diff.removed[0].receiver.as_ref().unwrap().type_name, "UserService");
+}
+
+#[test]
+fn test_package_change_replaces_all_symbols() {
+    let mut manager = LanguageManager::new();
+    let previous = parse(&mut manager, SAMPLE_GO);
+    assert_eq!(previous.package.as_deref(), Some("main"));
+
+    let changed = SAMPLE_GO.replacen("package main", "package users", 1);
+    let update = manager.reparse_file(&previous, &changed).unwrap();
+
+    assert!(update.diff.package_changed);
+    assert_eq!(update.result.package.as_deref(), Some("users"));
+    assert_eq!(update.diff.removed.len(), previous.symbols.len());
+    assert_eq!(update.diff.added.len(), update.result.symbols.len());
+    assert!(update.diff.modified.is_empty());
+}
+
+#[test]
+fn test_import_change_is_flagged() {
+    let mut manager = LanguageManager::new();
+    let previous = parse(&mut manager, SAMPLE_GO);
+
+    let changed = SAMPLE_GO.replacen("\"regexp\"", "\"os\"\n\t\"regexp\"", 1);
+    let update = manager.reparse_file(&previous, &changed).unwrap();
+
+    assert!(update.diff.imports_changed);
+    assert!(!update.diff.package_changed);
+    assert!(update.diff.added.is_empty() && update.diff.removed.is_empty() && update.diff.modified.is_empty());
+    assert!(update.result.imports.iter().any(|i| i.source == "os"));
+}
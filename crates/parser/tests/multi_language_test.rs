@@ -328,3 +328,22 @@ fn test_file_extension_detection() {
     // Unknown
     assert_eq!(manager.guess_language("file.unknown"), None);
 }
+
+#[cfg(feature = "go")]
+#[test]
+fn test_detect_language_in_fixtures() {
+    use synapse_parser::{detect_language, DetectedLanguage};
+
+    let content = include_bytes!("../../../tests/fixtures/multi-language/sample.go");
+
+    assert_eq!(
+        detect_language("sample.go", content),
+        DetectedLanguage::Known(SupportedLanguage::Go)
+    );
+
+    // 去掉扩展名后依靠 `package` 声明识别
+    assert_eq!(
+        detect_language("sample", content),
+        DetectedLanguage::Known(SupportedLanguage::Go)
+    );
+}
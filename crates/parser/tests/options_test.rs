@@ -0,0 +1,88 @@
+use std::fs;
+use std::path::PathBuf;
+use std::sync::atomic::AtomicBool;
+
+use synapse_parser::{parse_dir, FileResult, LanguageManager, ParseOptions};
+
+#[cfg(feature = "go")]
+const SAMPLE_GO: &str = include_str!("../../../tests/fixtures/multi-language/sample.go");
+
+/// 创建包含源码与测试文件的临时目录
+fn temp_project(name: &str) -> PathBuf {
+    let dir = std::env::temp_dir().join(format!("synapse-options-{}-{}", name, std::process::id()));
+    fs::create_dir_all(&dir).unwrap();
+    fs::write(dir.join("user.go"), "package main\n\nfunc NewUser() {}\n\nfunc helper() {}\n").unwrap();
+    fs::write(dir.join("user_test.go"), "package main\n\nfunc TestUser() {}\n\nfunc setup() {}\n").unwrap();
+    dir
+}
+
+fn run(dir: &PathBuf, options: ParseOptions) -> Vec<FileResult> {
+    let cancel = AtomicBool::new(false);
+    let results = parse_dir(dir, 2, &options, &cancel).unwrap();
+    fs::remove_dir_all(dir).unwrap();
+    results
+}
+
+fn symbol_names(results: &[FileResult]) -> Vec<String> {
+    results
+        .iter()
+        .flat_map(|r| r.result.iter().flat_map(|p| p.symbols.iter().map(|s| s.name.clone())))
+        .collect()
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_exported_only_on_fixture() {
+    let mut manager = LanguageManager::new();
+    let options = ParseOptions { exported_only: true, ..ParseOptions::default() };
+    let source = format!("{}\ntype userIndex struct{{}}\n\nfunc (s *UserService) lookup(id string) {{}}\n", SAMPLE_GO);
+    let result = manager.parse_file_with_options("sample.go", &source, &options).unwrap();
+    let names: Vec<&str> = result.symbols.iter().map(|s| s.name.as_str()).collect();
+
+    for kept in ["User", "UserService", "NewUserService", "GetUser", "ValidateEmail"] {
+        assert!(names.contains(&kept), "{} should be kept", kept);
+    }
+    for dropped in ["userIndex", "lookup"] {
+        assert!(!names.contains(&dropped), "{} should be dropped", dropped);
+    }
+    assert!(result.symbols.iter().all(|s| s.is_exported));
+
+    // 默认选项保留全部符号
+    let all = manager.parse_file("sample.go", &source).unwrap();
+    assert_eq!(all.symbols.len(), result.symbols.len() + 2);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_exclude_tests_in_parse_dir() {
+    let options = ParseOptions { exclude_tests: true, ..ParseOptions::default() };
+    let results = run(&temp_project("exclude"), options);
+
+    let paths: Vec<&str> = results.iter().map(|r| r.path.as_str()).collect();
+    assert_eq!(paths, ["user.go"]);
+    assert_eq!(symbol_names(&results), ["NewUser", "helper"]);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_default_options_include_tests() {
+    let results = run(&temp_project("default"), ParseOptions::default());
+
+    let paths: Vec<&str> = results.iter().map(|r| r.path.as_str()).collect();
+    assert_eq!(paths, ["user.go", "user_test.go"]);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_exported_only_and_exclude_tests_combined() {
+    let options = ParseOptions { exported_only: true, exclude_tests: true, ..ParseOptions::default() };
+    let results = run(&temp_project("combined"), options);
+
+    assert_eq!(symbol_names(&results), ["NewUser"]);
+
+    let exported_only = run(
+        &temp_project("exported"),
+        ParseOptions { exported_only: true, ..ParseOptions::default() },
+    );
+    assert_eq!(symbol_names(&exported_only), ["NewUser", "TestUser"]);
+}
@@ -0,0 +1,144 @@
+use std::fs;
+use std::path::PathBuf;
+use std::sync::atomic::AtomicBool;
+
+use synapse_parser::{parse_dir, ParseOptions, SkipReason};
+
+fn fixtures_dir() -> PathBuf {
+    PathBuf::from(env!("CARGO_MANIFEST_DIR")).join("../../tests/fixtures/multi-language")
+}
+
+#[test]
+fn test_parse_fixtures_dir() {
+    let cancel = AtomicBool::new(false);
+    let results = parse_dir(fixtures_dir(), 4, &ParseOptions::default(), &cancel).unwrap();
+
+    let paths: Vec<&str> = results.iter().map(|r| r.path.as_str()).collect();
+    let mut sorted = paths.clone();
+    sorted.sort();
+    assert_eq!(paths, sorted, "results should be sorted by path");
+
+    for expected in ["sample.ts", "sample.py", "sample.go", "sample.rs", "sample.java"] {
+        assert!(paths.contains(&expected), "missing {}", expected);
+    }
+    assert!(results.iter().all(|r| r.error.is_none()), "{:?}", results.iter().filter_map(|r| r.error.as_ref()).collect::<Vec<_>>());
+
+    let go = results.iter().find(|r| r.path == "sample.go").unwrap();
+    let go = go.result.as_ref().unwrap();
+    assert_eq!(go.language, "Go");
+    assert!(go.symbols.iter().any(|s| s.name == "UserService"));
+}
+
+#[test]
+fn test_parse_dir_is_deterministic_across_worker_counts() {
+    let cancel = AtomicBool::new(false);
+    let single = parse_dir(fixtures_dir(), 1, &ParseOptions::default(), &cancel).unwrap();
+    let many = parse_dir(fixtures_dir(), 8, &ParseOptions::default(), &cancel).unwrap();
+
+    let summary = |results: &[synapse_parser::FileResult]| -> Vec<(String, usize)> {
+        results
+            .iter()
+            .map(|r| (r.path.clone(), r.result.as_ref().map_or(0, |p| p.entities.len())))
+            .collect()
+    };
+    assert_eq!(summary(&single), summary(&many));
+}
+
+#[test]
+fn test_parse_dir_skips_unsupported_and_records_errors() {
+    let dir = std::env::temp_dir().join(format!("synapse-parse-dir-{}", std::process::id()));
+    fs::create_dir_all(dir.join("pkg")).unwrap();
+    fs::create_dir_all(dir.join(".git")).unwrap();
+    fs::write(dir.join("pkg/main.go"), "package main\n\nfunc main() {}\n").unwrap();
+    fs::write(dir.join("README.md"), "# readme\n").unwrap();
+    fs::write(dir.join("notes"), "plain text\n").unwrap();
+    fs::write(dir.join(".git/hook.py"), "print('hidden')\n").unwrap();
+    fs::write(dir.join("invalid.py"), [0xff, 0xfe, b'x']).unwrap();
+
+    let cancel = AtomicBool::new(false);
+    let results = parse_dir(&dir, 2, &ParseOptions::default(), &cancel).unwrap();
+    fs::remove_dir_all(&dir).unwrap();
+
+    let paths: Vec<&str> = results.iter().map(|r| r.path.as_str()).collect();
+    assert_eq!(paths, ["invalid.py", "pkg/main.go"]);
+    assert!(results[0].error.is_some() && results[0].result.is_none());
+    assert!(results[1].result.is_some());
+}
+
+#[test]
+fn test_parse_dir_skips_large_and_binary_files() {
+    let dir = std::env::temp_dir().join(format!("synapse-parse-dir-skip-{}", std::process::id()));
+    fs::create_dir_all(&dir).unwrap();
+    fs::write(dir.join("main.go"), "package main\n\nfunc main() {}\n").unwrap();
+    fs::write(dir.join("generated.go"), format!("package main\n\n// {}\n", "x".repeat(4096))).unwrap();
+    fs::write(dir.join("blob.py"), b"print('x')\n\x00\x01\x02").unwrap();
+    // 无扩展名的二进制文件不是源码，不出现在结果中
+    fs::write(dir.join("binary"), [0x7f, b'E', b'L', b'F', 0x00]).unwrap();
+
+    let cancel = AtomicBool::new(false);
+    let options = ParseOptions { max_file_bytes: 1024, ..ParseOptions::default() };
+    let results = parse_dir(&dir, 2, &options, &cancel).unwrap();
+    let unlimited = parse_dir(&dir, 2, &ParseOptions { max_file_bytes: 0, ..options }, &cancel).unwrap();
+    fs::remove_dir_all(&dir).unwrap();
+
+    let paths: Vec<&str> = results.iter().map(|r| r.path.as_str()).collect();
+    assert_eq!(paths, ["blob.py", "generated.go", "main.go"]);
+
+    assert_eq!(results[0].skipped, Some(SkipReason::Binary));
+    assert!(results[0].result.is_none() && results[0].error.is_none());
+    match &results[1].skipped {
+        Some(SkipReason::TooLarge { size, limit }) => {
+            assert!(*size > 4096);
+            assert_eq!(*limit, 1024);
+        }
+        other => panic!("expected TooLarge, got {:?}", other),
+    }
+    assert!(results[2].skipped.is_none() && results[2].result.is_some());
+
+    let skipped = results.iter().filter(|r| r.skipped.is_some()).count();
+    assert_eq!(skipped, 2);
+    assert!(unlimited[1].result.is_some(), "max_file_bytes = 0 disables the limit");
+
+    let json = serde_json::to_value(&results[1]).unwrap();
+    assert_eq!(json["skipped"]["reason"], "tooLarge");
+    assert_eq!(json["skipped"]["limit"], 1024);
+}
+
+#[test]
+fn test_parse_dir_cancelled() {
+    let cancel = AtomicBool::new(true);
+    assert!(parse_dir(fixtures_dir(), 2, &ParseOptions::default(), &cancel).is_err());
+}
+
+#[cfg(unix)]
+#[test]
+fn test_parse_dir_records_unreadable_subdirectory_and_continues() {
+    use std::os::unix::fs::PermissionsExt;
+
+    let dir = std::env::temp_dir().join(format!("synapse-parse-dir-locked-{}", std::process::id()));
+    fs::create_dir_all(dir.join("locked")).unwrap();
+    fs::create_dir_all(dir.join("pkg")).unwrap();
+    fs::write(dir.join("locked/hidden.go"), "package locked\n").unwrap();
+    fs::write(dir.join("pkg/main.go"), "package main\n\nfunc main() {}\n").unwrap();
+    fs::set_permissions(dir.join("locked"), fs::Permissions::from_mode(0o000)).unwrap();
+    // root 不受权限位限制，此时无法构造不可读目录
+    let readable = fs::read_dir(dir.join("locked")).is_ok();
+
+    let cancel = AtomicBool::new(false);
+    let results = parse_dir(&dir, 2, &ParseOptions::default(), &cancel);
+    fs::set_permissions(dir.join("locked"), fs::Permissions::from_mode(0o755)).unwrap();
+    fs::remove_dir_all(&dir).unwrap();
+    if readable {
+        return;
+    }
+
+    let results = results.unwrap();
+    let paths: Vec<&str> = results.iter().map(|r| r.path.as_str()).collect();
+    assert_eq!(paths, ["locked", "pkg/main.go"]);
+    assert!(results[0].result.is_none());
+    assert!(results[0].error.as_deref().unwrap().starts_with("Failed to read directory locked"));
+    assert!(results[1].result.is_some());
+
+    // 根目录不可读仍然整体失败
+    assert!(parse_dir(dir.join("missing"), 2, &ParseOptions::default(), &cancel).is_err());
+}
@@ -0,0 +1,104 @@
+#![cfg(feature = "go")]
+
+use std::io::{self, Read};
+
+use synapse_parser::{LanguageManager, ParseOptions, SupportedLanguage, Symbol};
+
+const SAMPLE_GO: &str = include_str!("../../../tests/fixtures/multi-language/sample.go");
+
+/// 每次只返回少量字节的 reader，模拟网络流（会把多字节 UTF-8 字符切开）
+struct ChunkedReader<'a> {
+    data: &'a [u8],
+    chunk: usize,
+}
+
+impl Read for ChunkedReader<'_> {
+    fn read(&mut self, buf: &mut [u8]) -> io::Result<usize> {
+        let n = self.chunk.min(buf.len()).min(self.data.len());
+        buf[..n].copy_from_slice(&self.data[..n]);
+        self.data = &self.data[n..];
+        Ok(n)
+    }
+}
+
+fn stream_symbols(path: &str, source: &str) -> Vec<Symbol> {
+    let mut manager = LanguageManager::new();
+    let reader = ChunkedReader { data: source.as_bytes(), chunk: 7 };
+
+    let mut symbols = Vec::new();
+    manager
+        .parse_stream(path, reader, SupportedLanguage::Go, &ParseOptions::default(), |symbol| {
+            symbols.push(symbol);
+            Ok(())
+        })
+        .unwrap();
+    symbols
+}
+
+#[test]
+fn test_stream_matches_full_parse() {
+    let mut manager = LanguageManager::new();
+    let full = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+    let streamed = stream_symbols("sample.go", SAMPLE_GO);
+
+    assert_eq!(streamed.len(), full.symbols.len());
+    for (a, b) in streamed.iter().zip(&full.symbols) {
+        assert_eq!(a.name, b.name);
+        assert_eq!(a.span, b.span);
+        assert_eq!(a.stable_id, b.stable_id);
+    }
+}
+
+#[test]
+fn test_stream_stable_ids_match_parse_file_for_nested_paths() {
+    let mut manager = LanguageManager::new();
+    let full = manager.parse_file("pkg/svc/user.go", SAMPLE_GO).unwrap();
+    let streamed = stream_symbols("pkg/svc/user.go", SAMPLE_GO);
+
+    let ids: Vec<&str> = streamed.iter().map(|s| s.stable_id.as_str()).collect();
+    let expected: Vec<&str> = full.symbols.iter().map(|s| s.stable_id.as_str()).collect();
+    assert_eq!(ids, expected);
+
+    // 同一包目录下换一个文件名 ID 不变，换目录则不同
+    let moved = stream_symbols("pkg/svc/other.go", SAMPLE_GO);
+    assert_eq!(moved[0].stable_id, streamed[0].stable_id);
+    let elsewhere = stream_symbols("pkg/other/user.go", SAMPLE_GO);
+    assert_ne!(elsewhere[0].stable_id, streamed[0].stable_id);
+}
+
+#[test]
+fn test_stream_spans_are_byte_accurate() {
+    let source = "package main\n\n// 你好 🌍\nfunc Greet() string {\n\treturn \"世界\"\n}\n";
+    let symbols = stream_symbols("greet.go", source);
+
+    let greet = &symbols[0];
+    assert_eq!(
+        &source[greet.span.start_byte..greet.span.end_byte],
+        "func Greet() string {\n\treturn \"世界\"\n}"
+    );
+    assert_eq!(greet.docstring.as_deref(), Some("你好 🌍"));
+}
+
+#[test]
+fn test_stream_emit_error_stops_parsing() {
+    let mut manager = LanguageManager::new();
+
+    let mut emitted = 0;
+    let result = manager.parse_stream("sample.go", SAMPLE_GO.as_bytes(), SupportedLanguage::Go, &ParseOptions::default(), |_| {
+        emitted += 1;
+        if emitted == 3 {
+            return Err("backpressure".to_string());
+        }
+        Ok(())
+    });
+
+    assert_eq!(result, Err("backpressure".to_string()));
+    assert_eq!(emitted, 3);
+}
+
+#[test]
+fn test_stream_rejects_unsupported_language() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_stream("x.ts", "let x = 1;".as_bytes(), SupportedLanguage::TypeScript, &ParseOptions::default(), |_| Ok(()));
+    assert!(result.is_err());
+}
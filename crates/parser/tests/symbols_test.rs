@@ -0,0 +1,771 @@
+use synapse_parser::{CallRef, LanguageManager, Param, SymbolKind, TypeParam};
+
+#[cfg(feature = "go")]
+const SAMPLE_GO: &str = include_str!("../../../tests/fixtures/multi-language/sample.go");
+#[cfg(feature = "go")]
+const DECLARATIONS_GO: &str = include_str!("../../../tests/fixtures/multi-language/declarations.go");
+#[cfg(feature = "go")]
+const GENERICS_GO: &str = include_str!("../../../tests/fixtures/multi-language/generics.go");
+#[cfg(feature = "python")]
+const SAMPLE_PY: &str = include_str!("../../../tests/fixtures/multi-language/sample.py");
+const SAMPLE_JS: &str = include_str!("../../../tests/fixtures/multi-language/sample.js");
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_methods_have_receivers() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+
+    for name in ["GetUser", "CreateUser", "DeleteUser"] {
+        let symbol = result.symbols.iter().find(|s| s.name == name).expect(name);
+        assert_eq!(symbol.kind, SymbolKind::Method, "{} should be a method", name);
+
+        let receiver = symbol.receiver.as_ref().expect("method should have a receiver");
+        assert_eq!(receiver.type_name, "UserService");
+        assert_eq!(receiver.name.as_deref(), Some("s"));
+        assert!(receiver.is_pointer);
+    }
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_free_functions_have_no_receiver() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+
+    for name in ["NewUserService", "ValidateEmail"] {
+        let symbol = result.symbols.iter().find(|s| s.name == name).expect(name);
+        assert_eq!(symbol.kind, SymbolKind::Function);
+        assert!(symbol.receiver.is_none());
+    }
+
+    let user = result.symbols.iter().find(|s| s.name == "User").unwrap();
+    assert_eq!(user.kind, SymbolKind::Type);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_value_receiver() {
+    let mut manager = LanguageManager::new();
+    let code = r#"
+package main
+
+type Point struct{ X, Y int }
+
+func (Point) Origin() bool { return true }
+"#;
+
+    let result = manager.parse_file("point.go", code).unwrap();
+    let method = result.symbols.iter().find(|s| s.name == "Origin").unwrap();
+    let receiver = method.receiver.as_ref().unwrap();
+
+    assert_eq!(receiver.type_name, "Point");
+    assert_eq!(receiver.name, None);
+    assert!(!receiver.is_pointer);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_struct_fields() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+
+    let user = result.symbols.iter().find(|s| s.name == "User").unwrap();
+    let fields: Vec<(&str, &str)> = user
+        .fields
+        .iter()
+        .map(|f| (f.name.as_str(), f.field_type.as_str()))
+        .collect();
+    assert_eq!(fields, [("ID", "string"), ("Name", "string"), ("Email", "string")]);
+    assert!(user.fields.iter().all(|f| f.tag.is_none() && !f.is_embedded));
+
+    let service = result.symbols.iter().find(|s| s.name == "UserService").unwrap();
+    let fields: Vec<(&str, &str)> = service
+        .fields
+        .iter()
+        .map(|f| (f.name.as_str(), f.field_type.as_str()))
+        .collect();
+    assert_eq!(fields, [("users", "map[string]*User"), ("mu", "sync.RWMutex")]);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_struct_field_tags() {
+    let mut manager = LanguageManager::new();
+    let code = r#"
+package main
+
+type User struct {
+    ID    string `json:"id"`
+    Email string `json:"email,omitempty" db:"email"`
+    Note  string
+}
+"#;
+
+    let result = manager.parse_file("tags.go", code).unwrap();
+    let user = result.symbols.iter().find(|s| s.name == "User").unwrap();
+    let tags: Vec<Option<&str>> = user.fields.iter().map(|f| f.tag.as_deref()).collect();
+
+    assert_eq!(tags, [Some(r#"json:"id""#), Some(r#"json:"email,omitempty" db:"email""#), None]);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_grouped_and_embedded_fields() {
+    let mut manager = LanguageManager::new();
+    let code = r#"
+package main
+
+type Point struct {
+    X, Y int
+    *Base
+    sync.Mutex
+}
+"#;
+
+    let result = manager.parse_file("point.go", code).unwrap();
+    let point = result.symbols.iter().find(|s| s.name == "Point").unwrap();
+    let names: Vec<&str> = point.fields.iter().map(|f| f.name.as_str()).collect();
+
+    assert_eq!(names, ["X", "Y", "Base", "Mutex"]);
+    assert!(!point.fields[0].is_embedded);
+    assert!(point.fields[2].is_embedded);
+    assert_eq!(point.fields[2].field_type, "*Base");
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_doc_comments() {
+    let mut manager = LanguageManager::new();
+    let code = r#"
+package main
+
+// UserPatch 描述部分更新，nil 字段保持不变
+type UserPatch struct{}
+
+// MemoryStore 是默认的内存 Store
+// 需要同时持有多个分片时按下标递增加锁
+type MemoryStore struct{}
+"#;
+
+    let result = manager.parse_file("doc.go", code).unwrap();
+    let doc = |name: &str| result.symbols.iter().find(|s| s.name == name).unwrap().docstring.clone();
+    assert_eq!(doc("UserPatch").as_deref(), Some("UserPatch 描述部分更新，nil 字段保持不变"));
+    assert_eq!(
+        doc("MemoryStore").as_deref(),
+        Some("MemoryStore 是默认的内存 Store\n需要同时持有多个分片时按下标递增加锁")
+    );
+
+    // 文件头注释后隔着 package/import，不应关联到 User
+    let sample = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+    let user = sample.symbols.iter().find(|s| s.name == "User").unwrap();
+    assert!(user.docstring.is_none());
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_doc_comment_association_rules() {
+    let mut manager = LanguageManager::new();
+    let code = r#"
+package main
+
+// 孤立注释
+
+func Detached() {}
+
+var x = 1 // 行尾注释
+func Trailing() {}
+
+/* 块注释
+ * 第二行
+ */
+func Block() {}
+
+type (
+    // Inner 分组内的注释
+    Inner struct{}
+)
+"#;
+
+    let result = manager.parse_file("doc.go", code).unwrap();
+    let doc = |name: &str| {
+        result
+            .symbols
+            .iter()
+            .find(|s| s.name == name)
+            .and_then(|s| s.docstring.clone())
+    };
+
+    assert_eq!(doc("Detached"), None);
+    assert_eq!(doc("Trailing"), None);
+    assert_eq!(doc("Block").as_deref(), Some("块注释\n第二行"));
+    assert_eq!(doc("Inner").as_deref(), Some("Inner 分组内的注释"));
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_imports() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+
+    let sources: Vec<&str> = result.imports.iter().map(|i| i.source.as_str()).collect();
+    assert_eq!(sources, ["errors", "regexp", "sync"]);
+    assert!(result.imports.iter().all(|i| i.alias.is_none() && i.file_path == "sample.go"));
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_import_aliases() {
+    let mut manager = LanguageManager::new();
+    let code = r#"
+package main
+
+import "os"
+
+import (
+    f "fmt"
+    . "strings"
+    _ "embed"
+)
+"#;
+
+    let result = manager.parse_file("imports.go", code).unwrap();
+    let imports: Vec<(&str, Option<&str>)> = result
+        .imports
+        .iter()
+        .map(|i| (i.source.as_str(), i.alias.as_deref()))
+        .collect();
+
+    assert_eq!(
+        imports,
+        [("os", None), ("fmt", Some("f")), ("strings", Some(".")), ("embed", Some("_"))]
+    );
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_symbol_span_matches_source() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+    let get_user = result.symbols.iter().find(|s| s.name == "GetUser").unwrap();
+
+    // 按源码定位 GetUser 的首行与其后第一个顶格 `}`
+    let lines: Vec<&str> = SAMPLE_GO.lines().collect();
+    let start = lines
+        .iter()
+        .position(|l| l.starts_with("func (s *UserService) GetUser("))
+        .unwrap();
+    let end = start + lines[start..].iter().position(|l| *l == "}").unwrap();
+
+    assert_eq!(get_user.span.start_line, start + 1);
+    assert_eq!(get_user.span.end_line, end + 1);
+
+    let body = &SAMPLE_GO[get_user.span.start_byte..get_user.span.end_byte];
+    assert_eq!(body, lines[start..=end].join("\n"));
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_span_byte_offsets_with_multibyte_source() {
+    let mut manager = LanguageManager::new();
+    let code = "package main\n\n// 问候 🌍\nvar greeting = \"你好，世界\"\n\nfunc Hello() string {\n\treturn greeting // ✓\n}\n";
+
+    let result = manager.parse_file("hello.go", code).unwrap();
+    let hello = result.symbols.iter().find(|s| s.name == "Hello").unwrap();
+
+    assert_eq!((hello.span.start_line, hello.span.end_line), (6, 8));
+    assert_eq!(
+        &code[hello.span.start_byte..hello.span.end_byte],
+        "func Hello() string {\n\treturn greeting // ✓\n}"
+    );
+}
+
+fn call(name: &str, receiver: Option<&str>) -> CallRef {
+    CallRef {
+        name: name.to_string(),
+        receiver: receiver.map(str::to_string),
+    }
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_method_calls() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+
+    let get_user = result.symbols.iter().find(|s| s.name == "GetUser").unwrap();
+    assert!(get_user.calls.contains(&call("RLock", Some("s.mu"))));
+    assert!(get_user.calls.contains(&call("RUnlock", Some("s.mu"))));
+    assert!(get_user.calls.contains(&call("New", Some("errors"))));
+
+    let create_user = result.symbols.iter().find(|s| s.name == "CreateUser").unwrap();
+    assert_eq!(create_user.calls, [call("Lock", Some("s.mu")), call("Unlock", Some("s.mu"))]);
+
+    // 类型声明没有调用
+    let user = result.symbols.iter().find(|s| s.name == "User").unwrap();
+    assert!(user.calls.is_empty());
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_function_calls() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+
+    let validate = result.symbols.iter().find(|s| s.name == "ValidateEmail").unwrap();
+    assert!(validate.calls.contains(&call("MatchString", Some("regexp"))));
+
+    let new_service = result.symbols.iter().find(|s| s.name == "NewUserService").unwrap();
+    assert_eq!(new_service.calls, [call("make", None)]);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_calls_are_deduplicated_and_include_closures() {
+    let mut manager = LanguageManager::new();
+    let code = r#"
+package main
+
+import "regexp"
+
+func Check(emails []string) bool {
+    for _, e := range emails {
+        if ok, _ := regexp.MatchString(`^.+@.+$`, e); !ok {
+            return false
+        }
+    }
+    defer func() { recover() }()
+    ok, _ := regexp.MatchString(`x`, "x")
+    return ok && len(emails) > 0
+}
+"#;
+
+    let result = manager.parse_file("check.go", code).unwrap();
+    let check = result.symbols.iter().find(|s| s.name == "Check").unwrap();
+    assert_eq!(
+        check.calls,
+        [call("MatchString", Some("regexp")), call("recover", None), call("len", None)]
+    );
+}
+
+fn stable_id_in(path: &str, code: &str, name: &str) -> String {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file(path, code).unwrap();
+    let symbol = result.symbols.iter().find(|s| s.name == name).expect(name);
+    assert_eq!(
+        symbol.stable_id,
+        symbol.compute_stable_id(&result.language, &result.file_path, result.package.as_deref())
+    );
+    symbol.stable_id.clone()
+}
+
+#[cfg(feature = "go")]
+fn stable_id_of(code: &str, name: &str) -> String {
+    stable_id_in("ids.go", code, name)
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_signatures_are_normalized() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+    let signature = |name: &str| {
+        result.symbols.iter().find(|s| s.name == name).unwrap().signature.clone()
+    };
+
+    assert_eq!(signature("GetUser"), "(string)(*User,error)");
+    assert_eq!(signature("ValidateEmail"), "(string)bool");
+    assert_eq!(signature("User"), "struct");
+}
+
+fn param(name: Option<&str>, param_type: &str, is_variadic: bool) -> Param {
+    Param {
+        name: name.map(str::to_string),
+        param_type: param_type.to_string(),
+        is_variadic,
+    }
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_params_and_returns() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+    let symbol = |name: &str| result.symbols.iter().find(|s| s.name == name).expect(name);
+
+    let get_user = symbol("GetUser");
+    assert_eq!(get_user.params, [param(Some("id"), "string", false)]);
+    assert_eq!(get_user.returns, ["*User", "error"]);
+
+    let create_user = symbol("CreateUser");
+    assert_eq!(create_user.params, [param(Some("user"), "*User", false)]);
+    assert_eq!(create_user.returns, ["error"]);
+
+    let validate_email = symbol("ValidateEmail");
+    assert_eq!(validate_email.params, [param(Some("email"), "string", false)]);
+    assert_eq!(validate_email.returns, ["bool"]);
+
+    assert!(symbol("User").params.is_empty() && symbol("User").returns.is_empty());
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_constants_variables_and_interfaces() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("declarations.go", DECLARATIONS_GO).unwrap();
+    let symbol = |name: &str| result.symbols.iter().find(|s| s.name == name).expect(name);
+    let text = |name: &str| {
+        let span = symbol(name).span;
+        &DECLARATIONS_GO[span.start_byte..span.end_byte]
+    };
+    let line_of = |prefix: &str| {
+        DECLARATIONS_GO
+            .lines()
+            .position(|l| l.trim_start().starts_with(prefix))
+            .unwrap()
+            + 1
+    };
+
+    // 分组常量：每个名字一个符号，span 为所在的 spec；省略类型的 iota 常量沿用上一行的类型
+    for name in ["RoleGuest", "RoleMember", "RoleAdmin"] {
+        let constant = symbol(name);
+        assert_eq!(constant.kind, SymbolKind::Constant, "{}", name);
+        assert_eq!(constant.signature, "Role", "{}", name);
+        assert_eq!(constant.span.start_line, line_of(name), "{}", name);
+    }
+    assert_eq!(text("RoleGuest"), "RoleGuest Role = iota");
+    assert_eq!(text("RoleMember"), "RoleMember");
+    assert_eq!(symbol("RoleGuest").docstring.as_deref(), Some("角色按权限从低到高排列"));
+    assert_eq!(symbol("RoleAdmin").docstring.as_deref(), Some("RoleAdmin 可以管理其他用户"));
+
+    let max = symbol("MaxNameLength");
+    assert_eq!(max.kind, SymbolKind::Constant);
+    assert_eq!(max.signature, "");
+    assert_eq!(text("MaxNameLength"), "MaxNameLength = 64");
+    assert_eq!(max.docstring.as_deref(), Some("MaxNameLength 限制用户名的字符数"));
+
+    // 包级变量：单个与分组形式，初始化表达式中的调用被记录
+    let default_role = symbol("defaultRole");
+    assert_eq!(default_role.kind, SymbolKind::Variable);
+    assert!(!default_role.is_exported);
+    assert_eq!(text("defaultRole"), "defaultRole = RoleGuest");
+
+    let err = symbol("ErrReadOnlyStore");
+    assert_eq!(err.kind, SymbolKind::Variable);
+    assert_eq!(err.span.start_line, line_of("ErrReadOnlyStore"));
+    assert_eq!(err.docstring.as_deref(), Some("ErrReadOnlyStore 由只读 Store 的写方法返回"));
+    assert_eq!(err.calls, [call("New", Some("errors"))]);
+    assert_eq!(symbol("roleNames").kind, SymbolKind::Variable);
+
+    // 函数体内的局部变量不是符号
+    assert!(result.symbols.iter().all(|s| s.name != "name" && s.name != "ok"));
+
+    // 接口：方法集按声明顺序，嵌入的接口以嵌入字段表示
+    let reader = symbol("UserReader");
+    assert_eq!(reader.kind, SymbolKind::Interface);
+    assert_eq!(reader.signature, "interface");
+    assert!(text("UserReader").starts_with("UserReader interface {"));
+    let methods: Vec<(&str, &str)> = reader
+        .methods
+        .iter()
+        .map(|m| (m.name.as_str(), m.signature.as_str()))
+        .collect();
+    assert_eq!(
+        methods,
+        [
+            ("Get", "(context.Context,string)(*User,error)"),
+            ("List", "(context.Context)([]*User,error)"),
+        ]
+    );
+    assert_eq!(
+        reader.methods[0].params,
+        [param(Some("ctx"), "context.Context", false), param(Some("id"), "string", false)]
+    );
+
+    let exporter = symbol("UserExporter");
+    assert_eq!(exporter.kind, SymbolKind::Interface);
+    assert_eq!(exporter.fields.len(), 1);
+    assert_eq!(exporter.fields[0].name, "UserReader");
+    assert!(exporter.fields[0].is_embedded);
+    assert_eq!(exporter.methods.len(), 1);
+    assert_eq!(exporter.methods[0].returns, ["int64", "error"]);
+
+    // 具名类型仍为 Type
+    assert_eq!(symbol("Role").kind, SymbolKind::Type);
+    assert_eq!(symbol("String").receiver.as_ref().unwrap().type_name, "Role");
+}
+
+#[cfg(feature = "go")]
+fn type_param(name: &str, constraint: &str) -> TypeParam {
+    TypeParam {
+        name: name.to_string(),
+        constraint: constraint.to_string(),
+    }
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_generics_type_params() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("generics.go", GENERICS_GO).unwrap();
+    let symbol = |name: &str| result.symbols.iter().find(|s| s.name == name).expect(name);
+
+    // `[T, U any]` 共享约束，展开为两个类型参数；类型参数不会混入普通参数
+    let map = symbol("Map");
+    assert_eq!(map.kind, SymbolKind::Function);
+    assert_eq!(map.type_params, [type_param("T", "any"), type_param("U", "any")]);
+    assert_eq!(
+        map.params,
+        [param(Some("items"), "[]T", false), param(Some("fn"), "func(T)U", false)]
+    );
+    assert_eq!(map.returns, ["[]U"]);
+    assert_eq!(map.signature, "[T,U any]([]T,func(T)U)[]U");
+
+    let keys = symbol("Keys");
+    assert_eq!(keys.type_params, [type_param("K", "comparable"), type_param("V", "any")]);
+    assert_eq!(keys.params, [param(Some("m"), "map[K]V", false)]);
+
+    let sum = symbol("Sum");
+    assert_eq!(sum.type_params, [type_param("N", "Number")]);
+    assert_eq!(sum.params, [param(Some("values"), "N", true)]);
+
+    // 泛型类型
+    let pair = symbol("Pair");
+    assert_eq!(pair.kind, SymbolKind::Type);
+    assert_eq!(pair.type_params, [type_param("K", "comparable"), type_param("V", "any")]);
+    assert_eq!(pair.signature, "[K comparable,V any]struct");
+    assert_eq!(pair.fields.len(), 2);
+
+    // 泛型接收者的方法记录基础类型名，本身没有类型参数
+    let push = symbol("Push");
+    assert_eq!(push.kind, SymbolKind::Method);
+    assert_eq!(push.receiver.as_ref().unwrap().type_name, "Stack");
+    assert!(push.type_params.is_empty());
+
+    // 约束接口与非泛型函数
+    assert!(symbol("Number").type_params.is_empty());
+    assert!(symbol("Labels").type_params.is_empty());
+    assert!(symbol("Labels").calls.contains(&call("Map", None)));
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_grouped_unnamed_and_variadic_params() {
+    let mut manager = LanguageManager::new();
+    let code = "package demo
+
+func Grouped(a, b int, rest ...string) (n, m int, err error) { return }
+
+func Unnamed(int, map[string] []int) {}
+";
+    let result = manager.parse_file("demo.go", code).unwrap();
+    let symbol = |name: &str| result.symbols.iter().find(|s| s.name == name).expect(name);
+
+    let grouped = symbol("Grouped");
+    assert_eq!(
+        grouped.params,
+        [
+            param(Some("a"), "int", false),
+            param(Some("b"), "int", false),
+            param(Some("rest"), "string", true),
+        ]
+    );
+    assert_eq!(grouped.returns, ["int", "int", "error"]);
+    assert_eq!(grouped.signature, "(int,int,...string)(int,int,error)");
+
+    let unnamed = symbol("Unnamed");
+    assert_eq!(unnamed.params, [param(None, "int", false), param(None, "map[string][]int", false)]);
+    assert!(unnamed.returns.is_empty());
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_stable_id_value_is_fixed() {
+    // 方案变化会导致索引中的所有 ID 失效，修改前需递增 STABLE_ID_VERSION
+    let code = "package demo\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n";
+    assert_eq!(stable_id_of(code, "Add"), "e3594f8c11bde883");
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_stable_id_ignores_whitespace_position_and_body() {
+    let original = "package demo\n\nfunc (s *Store) Get(id string) (*Item, error) {\n\treturn nil, nil\n}\n";
+    let reformatted = "package demo\n\n// Other 在前面插入，Get 的位置整体后移\nfunc Other() {}\n\nfunc (s  *Store)  Get( key string )  ( *Item,\n\terror ) {\n\treturn s.lookup(key)\n}\n";
+
+    assert_eq!(stable_id_of(original, "Get"), stable_id_of(reformatted, "Get"));
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_stable_id_changes_with_identity() {
+    let base = stable_id_of("package demo\n\nfunc (s *Store) Get(id string) error { return nil }\n", "Get");
+
+    let signature = stable_id_of("package demo\n\nfunc (s *Store) Get(id int) error { return nil }\n", "Get");
+    let receiver = stable_id_of("package demo\n\nfunc (c *Cache) Get(id string) error { return nil }\n", "Get");
+    let package = stable_id_of("package other\n\nfunc (s *Store) Get(id string) error { return nil }\n", "Get");
+
+    assert_ne!(base, signature);
+    assert_ne!(base, receiver);
+    assert_ne!(base, package);
+}
+
+#[cfg(feature = "go")]
+#[test]
+fn test_go_stable_id_is_scoped_by_package_directory() {
+    let main = "package main\n\nfunc main() {}\n";
+
+    // 不同目录下的同名包是不同的包
+    assert_ne!(stable_id_in("cmd/a/main.go", main, "main"), stable_id_in("cmd/b/main.go", main, "main"));
+
+    // 同一包内换文件不改变 ID；Windows 分隔符与 `/` 等价
+    let id = stable_id_in("cmd/a/main.go", main, "main");
+    assert_eq!(stable_id_in("cmd/a/app.go", main, "main"), id);
+    assert_eq!(stable_id_in("cmd\\a\\main.go", main, "main"), id);
+}
+
+#[test]
+fn test_stable_id_is_scoped_by_file_without_packages() {
+    let js = "export function validateEmail(email) {\n  return email.includes('@');\n}\n";
+    assert_ne!(
+        stable_id_in("src/a/validate.js", js, "validateEmail"),
+        stable_id_in("src/b/validate.js", js, "validateEmail")
+    );
+    assert_ne!(
+        stable_id_in("src/validate.js", js, "validateEmail"),
+        stable_id_in("src/email.js", js, "validateEmail")
+    );
+    assert_eq!(
+        stable_id_in("src/validate.js", js, "validateEmail"),
+        stable_id_in("src/validate.js", &format!("// moved\n{}", js), "validateEmail")
+    );
+}
+
+#[cfg(feature = "python")]
+#[test]
+fn test_python_stable_id_is_scoped_by_module_path() {
+    let py = "def validate_email(email):\n    return '@' in email\n";
+    assert_ne!(
+        stable_id_in("app/users.py", py, "validate_email"),
+        stable_id_in("app/accounts.py", py, "validate_email")
+    );
+}
+
+#[cfg(all(feature = "go", feature = "python"))]
+#[test]
+fn test_classes_and_structs_share_the_type_kind() {
+    let mut manager = LanguageManager::new();
+    let go = manager.parse_file("sample.go", SAMPLE_GO).unwrap();
+    let py = manager.parse_file("sample.py", SAMPLE_PY).unwrap();
+    let js = manager.parse_file("sample.js", SAMPLE_JS).unwrap();
+
+    for result in [&go, &py, &js] {
+        let service = result.symbols.iter().find(|s| s.name == "UserService").unwrap();
+        assert_eq!(service.kind, SymbolKind::Type, "{}", result.language);
+    }
+}
+
+#[cfg(feature = "python")]
+#[test]
+fn test_python_symbols() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.py", SAMPLE_PY).unwrap();
+    let symbol = |name: &str| result.symbols.iter().find(|s| s.name == name).expect(name);
+
+    let user = symbol("User");
+    assert_eq!(user.kind, SymbolKind::Type);
+    assert_eq!(user.signature, "class");
+    let fields: Vec<(&str, &str)> = user
+        .fields
+        .iter()
+        .map(|f| (f.name.as_str(), f.field_type.as_str()))
+        .collect();
+    assert_eq!(fields, [("id", "str"), ("name", "str"), ("email", "str")]);
+    // 装饰器包含在 span 中
+    assert!(SAMPLE_PY[user.span.start_byte..user.span.end_byte].starts_with("@dataclass"));
+
+    let service = symbol("UserService");
+    assert_eq!(service.docstring.as_deref(), Some("用户服务（与 sample.go 中的 UserService 对应）"));
+    assert_eq!(service.fields.len(), 1);
+    assert_eq!(service.fields[0].name, "max_batch");
+
+    for name in ["__init__", "get_user", "create_user", "delete_user", "shard_for", "_index_email"] {
+        let method = symbol(name);
+        assert_eq!(method.kind, SymbolKind::Method, "{} should be a method", name);
+        assert_eq!(method.receiver.as_ref().unwrap().type_name, "UserService");
+    }
+
+    let get_user = symbol("get_user");
+    assert_eq!(get_user.docstring.as_deref(), Some("获取用户信息"));
+    assert_eq!(get_user.signature, "(self,user_id:str)->Optional[User]");
+    assert_eq!(get_user.params, [param(Some("self"), "", false), param(Some("user_id"), "str", false)]);
+    assert_eq!(get_user.returns, ["Optional[User]"]);
+    assert_eq!(
+        symbol("list_users").params,
+        [
+            param(Some("self"), "", false),
+            param(Some("offset"), "int", false),
+            param(Some("limit"), "int", false),
+        ]
+    );
+    assert!(get_user.calls.contains(&call("get", Some("self.users"))));
+
+    let create_user = symbol("create_user");
+    assert!(create_user.calls.contains(&call("validator", Some("self"))));
+    assert!(create_user.calls.contains(&call("_index_email", Some("self"))));
+
+    let validate_email = symbol("validate_email");
+    assert_eq!(validate_email.kind, SymbolKind::Function);
+    assert!(validate_email.receiver.is_none());
+    assert!(validate_email.calls.contains(&call("match", Some("re"))));
+
+    assert!(symbol("__init__").is_exported);
+    assert!(!symbol("_index_email").is_exported);
+    assert!(!symbol("_normalize_email").is_exported);
+}
+
+#[test]
+fn test_javascript_symbols() {
+    let mut manager = LanguageManager::new();
+    let result = manager.parse_file("sample.js", SAMPLE_JS).unwrap();
+    let symbol = |name: &str| result.symbols.iter().find(|s| s.name == name).expect(name);
+
+    let service = symbol("UserService");
+    assert_eq!(service.kind, SymbolKind::Type);
+    assert!(service.is_exported);
+    assert_eq!(
+        service.docstring.as_deref(),
+        Some("UserService 管理用户，按 ID 存储并维护邮箱索引\n（与 sample.go 中的 UserService 对应）")
+    );
+    let fields: Vec<&str> = service.fields.iter().map(|f| f.name.as_str()).collect();
+    assert_eq!(fields, ["users", "byEmail", "#validator"]);
+
+    for name in ["constructor", "getUser", "createUser", "deleteUser", "findByEmail", "listUsers", "#indexEmail"] {
+        let method = symbol(name);
+        assert_eq!(method.kind, SymbolKind::Method, "{} should be a method", name);
+        assert_eq!(method.receiver.as_ref().unwrap().type_name, "UserService");
+    }
+
+    let get_user = symbol("getUser");
+    assert_eq!(get_user.docstring.as_deref(), Some("获取用户信息"));
+    assert_eq!(get_user.signature, "(id)");
+    assert_eq!(get_user.params, [param(Some("id"), "", false)]);
+    assert!(get_user.returns.is_empty());
+    assert_eq!(
+        symbol("listUsers").params,
+        [param(Some("offset"), "", false), param(Some("limit"), "", false)]
+    );
+    assert!(get_user.calls.contains(&call("get", Some("this.users"))));
+    assert!(symbol("createUser").calls.contains(&call("#indexEmail", Some("this"))));
+    assert!(!symbol("#indexEmail").is_exported);
+
+    let validate_email = symbol("validateEmail");
+    assert_eq!(validate_email.kind, SymbolKind::Function);
+    assert!(validate_email.is_exported);
+    assert!(validate_email.calls.contains(&call("test", Some("EMAIL_PATTERN"))));
+
+    // 箭头函数赋值视为函数，未导出
+    let normalize = symbol("normalizeEmail");
+    assert_eq!(normalize.kind, SymbolKind::Function);
+    assert_eq!(normalize.signature, "(email)");
+    assert!(!normalize.is_exported);
+}
@@ -0,0 +1,3 @@
+module github.com/fastmcp-me/nervusdb-mcp/examples/userservice
+
+go 1.22
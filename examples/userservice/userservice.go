@@ -0,0 +1,2022 @@
+// Package userservice 是带分片存储、变更通知与 TTL 的内存用户服务
+package userservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Version 由 UserService 维护，每次成功写入（创建、更新、删除、恢复）加一，可用作 ETag
+	Version uint64 `json:"version"`
+	// DeletedAt 仅在软删除模式下设置，非 nil 表示该用户是墓碑记录
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// ExpiresAt 由 CreateUserWithTTL 设置；到期后读取视为不存在，并由 janitor 清理
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// live 判断 user 是否存在且未被软删除
+func live(user *User) bool {
+	return user != nil && user.DeletedAt == nil
+}
+
+// expired 判断 user 是否设置了 TTL 且在 now 时已到期
+func expired(user *User, now time.Time) bool {
+	return user.ExpiresAt != nil && !now.Before(*user.ExpiresAt)
+}
+
+// UserPatch 描述部分更新，nil 字段保持不变
+type UserPatch struct {
+	Name  *string
+	Email *string
+}
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidEmail     = errors.New("invalid email")
+	ErrInvalidID        = errors.New("invalid user id")
+	ErrNilUser          = errors.New("user is nil")
+	ErrEmptyID          = errors.New("user id is empty")
+	ErrDuplicateID      = errors.New("duplicate user id in batch")
+	ErrNotDeleted       = errors.New("user is not soft-deleted")
+	ErrVersionConflict  = errors.New("user version conflict")
+	ErrDuplicateEmail   = errors.New("email already in use")
+	ErrInvalidTTL       = errors.New("ttl must be positive")
+	ErrUserExists       = errors.New("user already exists")
+	ErrOffsetOutOfRange = errors.New("offset out of range")
+)
+
+// VersionConflictError 描述 CompareAndUpdate 的版本不匹配；errors.Is 可匹配 ErrVersionConflict
+type VersionConflictError struct {
+	ID       string
+	Expected uint64
+	Actual   uint64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("user %q: expected version %d, found %d", e.ID, e.Expected, e.Actual)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// DuplicateEmailError 表示启用 WithUniqueEmails 时邮箱已属于另一个用户；errors.Is 可匹配 ErrDuplicateEmail
+type DuplicateEmailError struct {
+	Email   string
+	ID      string // 本次写入的用户
+	OwnerID string // 已使用该邮箱的用户
+}
+
+func (e *DuplicateEmailError) Error() string {
+	return fmt.Sprintf("user %q: email %q already belongs to user %q", e.ID, e.Email, e.OwnerID)
+}
+
+func (e *DuplicateEmailError) Is(target error) bool {
+	return target == ErrDuplicateEmail
+}
+
+type ChangeType int
+
+const (
+	ChangeCreated ChangeType = iota + 1
+	ChangeUpdated
+	ChangeDeleted
+	ChangeRestored
+	// ChangeExpired 表示 TTL 到期的用户被清理
+	ChangeExpired
+	// ChangeRenamed 表示用户 ID 从 PreviousID 改为 ID
+	ChangeRenamed
+)
+
+// ChangeEvent 描述一次已完成的变更，User 为变更时刻的快照副本
+type ChangeEvent struct {
+	Type ChangeType
+	ID   string
+	User User
+	// PreviousID 仅在 ChangeRenamed 时设置
+	PreviousID string
+}
+
+// Store 是 UserService 的存储后端（内存、文件或 NervusDB 等），实现需保证并发安全
+// Get 在用户不存在时返回 ErrUserNotFound；返回的 *User 视为只读
+type Store interface {
+	Get(ctx context.Context, id string) (*User, error)
+	// Put 写入用户，返回被覆盖的旧值（不存在时为 nil）
+	Put(ctx context.Context, user *User) (previous *User, err error)
+	// Delete 删除用户，返回被删除的值（不存在时为 nil）
+	Delete(ctx context.Context, id string) (removed *User, err error)
+	// List 返回全部用户，不保证顺序
+	List(ctx context.Context) ([]*User, error)
+	Len(ctx context.Context) (int, error)
+}
+
+// Ranger 是 Store 的可选扩展，支持不复制全部数据的遍历；fn 返回 false 时停止
+type Ranger interface {
+	Range(ctx context.Context, fn func(*User) bool) error
+}
+
+// Exister 是 Store 的可选扩展，只判断存在性而不取出用户
+type Exister interface {
+	Exists(ctx context.Context, id string) (bool, error)
+}
+
+const defaultShards = 32
+
+// userShard 是按 ID 哈希划分的一个分片，拥有独立的锁
+type userShard struct {
+	mu    sync.RWMutex
+	users map[string]*User
+}
+
+// MemoryStore 是默认的内存 Store，按 ID 哈希分片加锁
+// 需要同时持有多个分片时按下标递增加锁，避免死锁
+type MemoryStore struct {
+	shards []*userShard
+}
+
+func NewMemoryStore() *MemoryStore {
+	return NewShardedMemoryStore(defaultShards)
+}
+
+func NewShardedMemoryStore(shards int) *MemoryStore {
+	if shards < 1 {
+		shards = 1
+	}
+	m := &MemoryStore{shards: make([]*userShard, shards)}
+	for i := range m.shards {
+		m.shards[i] = &userShard{users: make(map[string]*User)}
+	}
+	return m
+}
+
+// ShardFor 返回 id 所在的分片下标（0 <= 下标 < shards），与 NewShardedMemoryStore 的分片方式一致，
+// 可供外部路由在多个 UserService 实例或节点之间划分用户
+// 算法固定为 FNV-1a 32 位哈希对 shards 取模，不依赖 Go 的 map 哈希，因此跨进程重启与 Go 版本保持稳定；
+// shards < 1 时按 1 处理
+func ShardFor(id string, shards int) int {
+	if shards < 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(shards))
+}
+
+func (m *MemoryStore) shardFor(id string) *userShard {
+	return m.shards[ShardFor(id, len(m.shards))]
+}
+
+// rlockAll 按下标顺序获取所有分片的读锁，返回对应的解锁函数
+func (m *MemoryStore) rlockAll() func() {
+	for _, sh := range m.shards {
+		sh.mu.RLock()
+	}
+	return func() {
+		for i := len(m.shards) - 1; i >= 0; i-- {
+			m.shards[i].mu.RUnlock()
+		}
+	}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*User, error) {
+	sh := m.shardFor(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	user, ok := sh.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Exists 只持有 ID 所在分片的读锁
+func (m *MemoryStore) Exists(ctx context.Context, id string) (bool, error) {
+	sh := m.shardFor(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	_, ok := sh.users[id]
+	return ok, nil
+}
+
+func (m *MemoryStore) Put(ctx context.Context, user *User) (*User, error) {
+	sh := m.shardFor(user.ID)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	previous := sh.users[user.ID]
+	sh.users[user.ID] = user
+	return previous, nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) (*User, error) {
+	sh := m.shardFor(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	removed := sh.users[id]
+	delete(sh.users, id)
+	return removed, nil
+}
+
+func (m *MemoryStore) List(ctx context.Context) ([]*User, error) {
+	unlock := m.rlockAll()
+	defer unlock()
+
+	users := make([]*User, 0, m.lenLocked())
+	for _, sh := range m.shards {
+		for _, user := range sh.users {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// Range 逐个分片持有读锁遍历
+func (m *MemoryStore) Range(ctx context.Context, fn func(*User) bool) error {
+	for _, sh := range m.shards {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !m.rangeShard(sh, fn) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) rangeShard(sh *userShard, fn func(*User) bool) bool {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	for _, user := range sh.users {
+		if !fn(user) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MemoryStore) Len(ctx context.Context) (int, error) {
+	unlock := m.rlockAll()
+	defer unlock()
+
+	return m.lenLocked(), nil
+}
+
+func (m *MemoryStore) lenLocked() int {
+	n := 0
+	for _, sh := range m.shards {
+		n += len(sh.users)
+	}
+	return n
+}
+
+type UserService struct {
+	store Store
+	// mu 串行化写操作并保护 byEmail 与 names；读路径直接访问 store，由 store 自行加锁
+	mu sync.RWMutex
+	// byEmail 将小写邮箱映射到 ID 列表（按创建顺序，最后一个为最新）
+	byEmail map[string][]string
+	names   NameIndex
+
+	emailValidator EmailValidator
+	idValidator    IDValidator
+	foldLocalPart  bool
+	softDelete     bool
+	uniqueEmails   bool
+	now            func() time.Time
+	// hasTTL 在写入过带 TTL 的用户后置位，此后 Exists/Count 不再走忽略过期时间的快速路径
+	hasTTL atomic.Bool
+
+	metrics   Metrics
+	timeLocks bool
+	// lockWait 是当前写锁持有者的等待时长，在 s.mu 下读写，解锁后再上报
+	lockWait time.Duration
+
+	subMu       sync.RWMutex
+	subscribers []func(ChangeEvent)
+
+	// janitor 在第一次 CreateUserWithTTL 时启动，Close 关闭 stop 并等待 janitorDone
+	sweepInterval time.Duration
+	janitorOnce   sync.Once
+	closeOnce     sync.Once
+	stop          chan struct{}
+	janitorDone   chan struct{}
+}
+
+// Option 配置 UserService
+type Option func(*UserService)
+
+// WithEmailValidator 替换创建/更新路径使用的邮箱校验策略
+func WithEmailValidator(v EmailValidator) Option {
+	return func(s *UserService) {
+		s.emailValidator = v
+	}
+}
+
+// WithIDValidator 替换创建/更新路径使用的 ID 校验策略，例如要求 UUID 或带前缀的 ID
+func WithIDValidator(v IDValidator) Option {
+	return func(s *UserService) {
+		s.idValidator = v
+	}
+}
+
+// WithNameIndex 替换 SearchByNamePrefix 使用的名字索引实现（如 trie）
+func WithNameIndex(idx NameIndex) Option {
+	return func(s *UserService) {
+		s.names = idx
+	}
+}
+
+// WithMetrics 接入调用计数与写锁等待时长的上报（如 Prometheus、OpenTelemetry）
+func WithMetrics(m Metrics) Option {
+	return func(s *UserService) {
+		s.metrics = m
+	}
+}
+
+// WithSoftDelete 让删除只写入墓碑（DeletedAt），可用 Restore 恢复、Purge 永久清理
+// 未启用时删除直接从 store 移除
+func WithSoftDelete() Option {
+	return func(s *UserService) {
+		s.softDelete = true
+	}
+}
+
+// WithUniqueEmails 为 true 时，CreateUser/UpdateUser/CreateUsers/Restore 拒绝写入已属于其他 ID 的邮箱（忽略大小写），
+// 返回 *DuplicateEmailError；检查与写入在同一次写锁内完成。默认允许多个用户共享邮箱
+func WithUniqueEmails(unique bool) Option {
+	return func(s *UserService) {
+		s.uniqueEmails = unique
+	}
+}
+
+// WithSweepInterval 设置 janitor 清理过期用户的间隔，默认一分钟
+// d <= 0 时不启动 janitor，过期用户只在读取时隐藏，需要调用方定期调用 EvictExpired
+func WithSweepInterval(d time.Duration) Option {
+	return func(s *UserService) {
+		s.sweepInterval = d
+	}
+}
+
+// WithClock 替换获取当前时间的函数（TTL 到期判断、软删除时间戳），用于测试中的假时钟
+func WithClock(now func() time.Time) Option {
+	return func(s *UserService) {
+		s.now = now
+	}
+}
+
+// WithCaseInsensitiveLocalPart 规范化邮箱时连同本地部分一起转小写
+// RFC 5321 规定本地部分区分大小写，因此默认只转换域名
+func WithCaseInsensitiveLocalPart() Option {
+	return func(s *UserService) {
+		s.foldLocalPart = true
+	}
+}
+
+func NewUserService(opts ...Option) *UserService {
+	return newUserService(NewMemoryStore(), opts)
+}
+
+func NewUserServiceSharded(shards int, opts ...Option) *UserService {
+	return newUserService(NewShardedMemoryStore(shards), opts)
+}
+
+// NewUserServiceWithStore 使用自定义 Store，并根据其中已有的数据重建邮箱与名字索引
+func NewUserServiceWithStore(ctx context.Context, store Store, opts ...Option) (*UserService, error) {
+	s := newUserService(store, opts)
+	users, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if live(user) {
+			s.index(user)
+		}
+		if user.ExpiresAt != nil {
+			s.hasTTL.Store(true)
+		}
+	}
+	if s.hasTTL.Load() {
+		s.startJanitor()
+	}
+	return s, nil
+}
+
+func newUserService(store Store, opts []Option) *UserService {
+	s := &UserService{
+		store:          store,
+		byEmail:        make(map[string][]string),
+		names:          newSortedNameIndex(),
+		emailValidator: DefaultEmailValidator,
+		idValidator:    DefaultIDValidator,
+		now:            time.Now,
+		metrics:        nopMetrics{},
+		sweepInterval:  defaultSweepInterval,
+		stop:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	_, nop := s.metrics.(nopMetrics)
+	s.timeLocks = !nop
+	return s
+}
+
+// Metrics 接收 UserService 的运行指标，实现需并发安全且不应阻塞
+// 写操作按 ChangeEvent 计数：覆盖写入、Restore 与 Rename 计为 Update，TTL 清理计为 Delete；ObserveLockWait 只统计写锁
+type Metrics interface {
+	IncrGet()
+	IncrMiss()
+	IncrCreate()
+	IncrUpdate()
+	IncrDelete()
+	ObserveLockWait(d time.Duration)
+}
+
+// nopMetrics 是默认实现；识别到它时 lock 不会调用 time.Now
+type nopMetrics struct{}
+
+func (nopMetrics) IncrGet()                      {}
+func (nopMetrics) IncrMiss()                     {}
+func (nopMetrics) IncrCreate()                   {}
+func (nopMetrics) IncrUpdate()                   {}
+func (nopMetrics) IncrDelete()                   {}
+func (nopMetrics) ObserveLockWait(time.Duration) {}
+
+// lock 获取写锁并记录等待时长；上报推迟到 unlock 释放锁之后，不占用临界区
+func (s *UserService) lock() {
+	if !s.timeLocks {
+		s.mu.Lock()
+		return
+	}
+	start := time.Now()
+	s.mu.Lock()
+	s.lockWait = time.Since(start)
+}
+
+func (s *UserService) unlock() {
+	wait := s.lockWait
+	s.lockWait = 0
+	s.mu.Unlock()
+	if s.timeLocks {
+		s.metrics.ObserveLockWait(wait)
+	}
+}
+
+// ReadOption 调整读取行为
+type ReadOption func(*readOptions)
+
+type readOptions struct {
+	includeDeleted bool
+}
+
+// IncludeDeleted 让 GetUser/List 同时返回软删除的墓碑记录
+func IncludeDeleted() ReadOption {
+	return func(o *readOptions) {
+		o.includeDeleted = true
+	}
+}
+
+func collectReadOptions(opts []ReadOption) readOptions {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// GetUser 返回用户的副本，修改返回值不会影响 store；默认跳过软删除的用户
+func (s *UserService) GetUser(id string, opts ...ReadOption) (*User, error) {
+	return s.GetUserCtx(context.Background(), id, opts...)
+}
+
+// GetUserCtx 在获取锁之前检查 ctx，已取消时直接返回 ctx.Err()
+func (s *UserService) GetUserCtx(ctx context.Context, id string, opts ...ReadOption) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	user, err := s.lookup(ctx, id, collectReadOptions(opts).includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return cloneUser(user), nil
+}
+
+// GetUsers 批量获取用户副本：只获取一次读锁，所有 ID 在同一时刻的状态下解析
+// missing 按输入顺序列出不存在（含软删除）的 ID，重复的 ID 只出现一次；store 出错的 ID 也视为缺失
+func (s *UserService) GetUsers(ids []string) (found map[string]*User, missing []string) {
+	ctx := context.Background()
+	found = make(map[string]*User, len(ids))
+	seen := make(map[string]bool, len(ids))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		user, err := s.lookup(ctx, id, false)
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		found[id] = cloneUser(user)
+	}
+	return found, missing
+}
+
+// GetUserRef 返回 store 内部的指针，省去一次复制；软删除的用户视为不存在
+//
+// 警告：返回值必须视为只读。直接修改字段会绕过校验、锁和邮箱索引，破坏 store 的一致性；
+// 需要修改时使用 UpdateUser
+func (s *UserService) GetUserRef(id string) (*User, error) {
+	return s.lookup(context.Background(), id, false)
+}
+
+// lookup 读取 store 内部的用户并上报 Get/Miss；软删除的用户仅在 includeDeleted 时返回，过期的用户始终视为不存在
+func (s *UserService) lookup(ctx context.Context, id string, includeDeleted bool) (*User, error) {
+	s.metrics.IncrGet()
+	user, err := s.store.Get(ctx, id)
+	if err == nil && (expired(user, s.now()) || !live(user) && !includeDeleted) {
+		err = ErrUserNotFound
+	}
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			s.metrics.IncrMiss()
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func cloneUser(user *User) *User {
+	clone := *user
+	return &clone
+}
+
+// CreateUser 写入用户；created 仅在该 ID 之前不存在时为 true，否则为覆盖
+func (s *UserService) CreateUser(user *User) (created bool, err error) {
+	return s.CreateUserCtx(context.Background(), user)
+}
+
+func (s *UserService) CreateUserCtx(ctx context.Context, user *User) (created bool, err error) {
+	return s.create(ctx, user, nil)
+}
+
+// CreateUserWithTTL 与 CreateUser 相同，但用户在 ttl 之后过期：读取视为不存在，并由后台 janitor 清理
+// （触发 ChangeExpired 事件）。之后的 UpdateUser 保留过期时间，CreateUser 覆盖写入则清除它
+func (s *UserService) CreateUserWithTTL(user *User, ttl time.Duration) (created bool, err error) {
+	if ttl <= 0 {
+		return false, ErrInvalidTTL
+	}
+	expiresAt := s.now().Add(ttl)
+	s.hasTTL.Store(true)
+	created, err = s.create(context.Background(), user, &expiresAt)
+	if err == nil {
+		s.startJanitor()
+	}
+	return created, err
+}
+
+// create 是 CreateUserCtx/CreateUserWithTTL 的共同实现，expiresAt 为 nil 表示永不过期
+func (s *UserService) create(ctx context.Context, user *User, expiresAt *time.Time) (created bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if user == nil {
+		return false, ErrNilUser
+	}
+	if err := s.validateID(user.ID); err != nil {
+		return false, err
+	}
+	email, err := s.prepareEmail(user.Email)
+	if err != nil {
+		return false, err
+	}
+	normalized := *user
+	normalized.Email = email
+	normalized.DeletedAt = nil
+	normalized.ExpiresAt = expiresAt
+	user = &normalized
+
+	s.lock()
+	if err := s.checkEmailOwner(user.Email, user.ID); err != nil {
+		s.unlock()
+		return false, err
+	}
+	previous, err := s.putVersioned(ctx, user, 0)
+	if err != nil {
+		s.unlock()
+		return false, err
+	}
+	if live(previous) {
+		s.unindex(previous)
+	}
+	s.index(user)
+	// 覆盖墓碑或已过期的用户等同于重新创建
+	replaced := s.visible(previous)
+	s.unlock()
+
+	evt := ChangeEvent{Type: ChangeCreated, ID: user.ID, User: *user}
+	if replaced {
+		evt.Type = ChangeUpdated
+	}
+	s.emit(evt)
+	return !replaced, nil
+}
+
+func (s *UserService) UpdateUser(id string, patch UserPatch) (*User, error) {
+	return s.update(id, nil, patch)
+}
+
+// putVersioned 在现有版本（含墓碑）基础上加一后写入，调用方需持有 s.mu 写锁
+// 被 Purge 清理后重新创建的用户从版本 1 重新开始；minVersion 大于该版本时改用 minVersion
+func (s *UserService) putVersioned(ctx context.Context, user *User, minVersion uint64) (previous *User, err error) {
+	current, err := s.store.Get(ctx, user.ID)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		user.Version = 1
+	case err != nil:
+		return nil, err
+	default:
+		user.Version = current.Version + 1
+	}
+	user.Version = max(user.Version, minVersion)
+	return s.store.Put(ctx, user)
+}
+
+// CompareAndUpdate 仅当存储中的版本等于 expectedVersion 时应用 patch，否则返回 *VersionConflictError
+// 版本检查与写入在同一次写锁内完成，适合实现 HTTP 的 If-Match
+func (s *UserService) CompareAndUpdate(id string, expectedVersion uint64, patch UserPatch) (*User, error) {
+	return s.update(id, &expectedVersion, patch)
+}
+
+// update 是 UpdateUser/CompareAndUpdate 的共同实现，expectedVersion 为 nil 时不检查版本
+func (s *UserService) update(id string, expectedVersion *uint64, patch UserPatch) (*User, error) {
+	if err := s.validateID(id); err != nil {
+		return nil, err
+	}
+	var email string
+	if patch.Email != nil {
+		var err error
+		if email, err = s.prepareEmail(*patch.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := context.Background()
+	s.lock()
+	existing, err := s.store.Get(ctx, id)
+	if err != nil {
+		s.unlock()
+		return nil, err
+	}
+	if !s.visible(existing) {
+		s.unlock()
+		return nil, ErrUserNotFound
+	}
+	if expectedVersion != nil && *expectedVersion != existing.Version {
+		s.unlock()
+		return nil, &VersionConflictError{ID: id, Expected: *expectedVersion, Actual: existing.Version}
+	}
+	if patch.Email != nil {
+		if err := s.checkEmailOwner(email, id); err != nil {
+			s.unlock()
+			return nil, err
+		}
+	}
+
+	updated := *existing
+	updated.Version++
+	if patch.Name != nil {
+		updated.Name = *patch.Name
+	}
+	if patch.Email != nil {
+		updated.Email = email
+	}
+	if _, err := s.store.Put(ctx, &updated); err != nil {
+		s.unlock()
+		return nil, err
+	}
+	s.reindex(existing, &updated)
+	s.unlock()
+
+	s.emit(ChangeEvent{Type: ChangeUpdated, ID: id, User: updated})
+	result := updated
+	return &result, nil
+}
+
+func (s *UserService) DeleteUser(id string) bool {
+	_, exists := s.Remove(id)
+	return exists
+}
+
+func (s *UserService) DeleteUserCtx(ctx context.Context, id string) (bool, error) {
+	removed, err := s.remove(ctx, id)
+	return removed != nil, err
+}
+
+// Remove 在同一次写锁内删除并返回被删除的用户，避免先 GetUser 再删除的竞态
+func (s *UserService) Remove(id string) (*User, bool) {
+	removed, err := s.remove(context.Background(), id)
+	if err != nil || removed == nil {
+		return nil, false
+	}
+	return removed, true
+}
+
+func (s *UserService) remove(ctx context.Context, id string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.lock()
+	removed, err := s.deleteLocked(ctx, id)
+	if err != nil {
+		s.unlock()
+		return nil, err
+	}
+	if removed == nil {
+		s.unlock()
+		return nil, nil
+	}
+	s.unindex(removed)
+	wasExpired := expired(removed, s.now())
+	s.unlock()
+
+	// 删除已过期但尚未清理的用户等同于提前清理，对调用方而言用户不存在
+	if wasExpired {
+		s.emit(ChangeEvent{Type: ChangeExpired, ID: id, User: *removed})
+		return nil, nil
+	}
+	s.emit(ChangeEvent{Type: ChangeDeleted, ID: id, User: *removed})
+	return removed, nil
+}
+
+// deleteLocked 按删除模式移除用户或写入墓碑，返回删除前的用户（不存在或已是墓碑时为 nil）
+// 调用方需持有 s.mu 写锁
+func (s *UserService) deleteLocked(ctx context.Context, id string) (*User, error) {
+	if !s.softDelete {
+		removed, err := s.store.Delete(ctx, id)
+		if err != nil || !live(removed) {
+			return nil, err
+		}
+		return removed, nil
+	}
+
+	existing, err := s.store.Get(ctx, id)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil || !live(existing) {
+		return nil, err
+	}
+	tombstone := *existing
+	deletedAt := s.now()
+	tombstone.DeletedAt = &deletedAt
+	tombstone.Version++
+	if _, err := s.store.Put(ctx, &tombstone); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// Restore 撤销软删除；用户不存在时返回 ErrUserNotFound，未被删除时返回 ErrNotDeleted
+func (s *UserService) Restore(id string) (*User, error) {
+	ctx := context.Background()
+
+	s.lock()
+	existing, err := s.store.Get(ctx, id)
+	if err != nil {
+		s.unlock()
+		return nil, err
+	}
+	if expired(existing, s.now()) {
+		s.unlock()
+		return nil, ErrUserNotFound
+	}
+	if live(existing) {
+		s.unlock()
+		return nil, ErrNotDeleted
+	}
+	// 删除期间邮箱可能已被其他用户使用
+	if err := s.checkEmailOwner(existing.Email, id); err != nil {
+		s.unlock()
+		return nil, err
+	}
+	restored := *existing
+	restored.DeletedAt = nil
+	restored.Version++
+	if _, err := s.store.Put(ctx, &restored); err != nil {
+		s.unlock()
+		return nil, err
+	}
+	s.index(&restored)
+	s.unlock()
+
+	s.emit(ChangeEvent{Type: ChangeRestored, ID: id, User: restored})
+	return cloneUser(&restored), nil
+}
+
+// Rename 在同一次写锁内把用户从 oldID 移到 newID，邮箱与名字索引随之更新，只触发一次 ChangeRenamed 事件
+// oldID 不存在时返回 ErrUserNotFound，newID 已被未删除的用户占用时返回 ErrUserExists；
+// newID 上的墓碑或已过期用户会被覆盖，此时 Version 取两者中较大者加一，保证 newID 的版本只增不减；
+// 否则 Version 在原记录基础上加一。ExpiresAt 保持不变
+func (s *UserService) Rename(oldID, newID string) error {
+	if err := s.validateID(oldID); err != nil {
+		return err
+	}
+	if err := s.validateID(newID); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	s.lock()
+	existing, err := s.store.Get(ctx, oldID)
+	if errors.Is(err, ErrUserNotFound) || err == nil && !s.visible(existing) {
+		s.unlock()
+		return ErrUserNotFound
+	}
+	if err != nil {
+		s.unlock()
+		return err
+	}
+	if oldID == newID {
+		s.unlock()
+		return nil
+	}
+	target, err := s.store.Get(ctx, newID)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		target = nil
+	case err != nil:
+		s.unlock()
+		return err
+	case s.visible(target):
+		s.unlock()
+		return ErrUserExists
+	}
+
+	renamed := *existing
+	renamed.ID = newID
+	if target != nil {
+		renamed.Version = max(renamed.Version, target.Version)
+	}
+	renamed.Version++
+	if _, err := s.store.Put(ctx, &renamed); err != nil {
+		s.unlock()
+		return err
+	}
+	if _, err := s.store.Delete(ctx, oldID); err != nil {
+		// 撤销写入，恢复 newID 上原有的记录
+		if target != nil {
+			s.store.Put(ctx, target)
+		} else {
+			s.store.Delete(ctx, newID)
+		}
+		s.unlock()
+		return err
+	}
+	if live(target) {
+		s.unindex(target)
+	}
+	s.unindex(existing)
+	s.index(&renamed)
+	s.unlock()
+
+	s.emit(ChangeEvent{Type: ChangeRenamed, ID: newID, PreviousID: oldID, User: renamed})
+	return nil
+}
+
+// Purge 永久删除软删除时间早于 age 之前的墓碑并返回清理数量
+// 删除事件已在软删除时发出，Purge 不再发出事件
+func (s *UserService) Purge(age time.Duration) int {
+	ctx := context.Background()
+	cutoff := s.now().Add(-age)
+
+	s.lock()
+	defer s.unlock()
+
+	users, err := s.store.List(ctx)
+	if err != nil {
+		return 0
+	}
+	purged := 0
+	for _, user := range users {
+		if live(user) || user.DeletedAt.After(cutoff) {
+			continue
+		}
+		if removed, err := s.store.Delete(ctx, user.ID); err == nil && removed != nil {
+			purged++
+		}
+	}
+	return purged
+}
+
+// visible 判断用户对读取方是否可见：存在、未被软删除且未过期
+func (s *UserService) visible(user *User) bool {
+	return live(user) && !expired(user, s.now())
+}
+
+const defaultSweepInterval = time.Minute
+
+// EvictExpired 立即从 store 移除所有已过期的用户（含过期的墓碑）并返回移除数量
+// 未删除的用户各触发一次 ChangeExpired 事件；janitor 按 sweep 间隔调用它
+func (s *UserService) EvictExpired() int {
+	ctx := context.Background()
+	now := s.now()
+
+	s.lock()
+	users, err := s.store.List(ctx)
+	if err != nil {
+		s.unlock()
+		return 0
+	}
+	evicted := 0
+	var events []ChangeEvent
+	for _, user := range users {
+		if !expired(user, now) {
+			continue
+		}
+		removed, err := s.store.Delete(ctx, user.ID)
+		if err != nil || removed == nil {
+			continue
+		}
+		evicted++
+		// 墓碑的删除事件已在软删除时发出
+		if live(removed) {
+			s.unindex(removed)
+			events = append(events, ChangeEvent{Type: ChangeExpired, ID: removed.ID, User: *removed})
+		}
+	}
+	s.unlock()
+
+	for _, evt := range events {
+		s.emit(evt)
+	}
+	return evicted
+}
+
+// startJanitor 启动后台清理 goroutine；只启动一次，Close 之后或 sweep 间隔 <= 0 时不启动
+func (s *UserService) startJanitor() {
+	if s.sweepInterval <= 0 {
+		return
+	}
+	s.janitorOnce.Do(func() {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		s.janitorDone = make(chan struct{})
+		go s.runJanitor(s.janitorDone)
+	})
+}
+
+func (s *UserService) runJanitor(done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.EvictExpired()
+		}
+	}
+}
+
+// Close 停止 janitor 并等待其退出，可重复调用；Close 之后 UserService 仍可使用，但过期用户不再被自动清理
+func (s *UserService) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	// 与 startJanitor 共用 janitorOnce：等待进行中的启动完成，并阻止之后再启动
+	s.janitorOnce.Do(func() {})
+	if s.janitorDone != nil {
+		<-s.janitorDone
+	}
+	return nil
+}
+
+// DeleteWhere 删除所有满足 pred 的用户并返回删除数量；pred 收到的是副本
+func (s *UserService) DeleteWhere(pred func(*User) bool) int {
+	ctx := context.Background()
+
+	s.lock()
+	users, err := s.store.List(ctx)
+	if err != nil {
+		s.unlock()
+		return 0
+	}
+	var events []ChangeEvent
+	for _, user := range users {
+		if !s.visible(user) {
+			continue
+		}
+		snapshot := *user
+		if !pred(&snapshot) {
+			continue
+		}
+		removed, err := s.deleteLocked(ctx, user.ID)
+		if err != nil || removed == nil {
+			continue
+		}
+		s.unindex(removed)
+		events = append(events, ChangeEvent{Type: ChangeDeleted, ID: removed.ID, User: *removed})
+	}
+	s.unlock()
+
+	for _, evt := range events {
+		s.emit(evt)
+	}
+	return len(events)
+}
+
+// prepareEmail 规范化邮箱后再交给校验器，返回应写入存储的规范形式
+func (s *UserService) prepareEmail(email string) (string, error) {
+	normalized, err := normalizeEmail(email, s.foldLocalPart)
+	if err != nil {
+		return "", err
+	}
+	if err := s.validateEmail(normalized); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
+
+// validateEmail 使用配置的校验器；自定义策略拒绝但格式合法时返回 ErrEmailRejected
+func (s *UserService) validateEmail(email string) error {
+	if s.emailValidator.Validate(email) {
+		return nil
+	}
+	if err := ValidateEmailDetailed(email); err != nil {
+		return err
+	}
+	return &EmailError{Email: email, Err: ErrEmailRejected}
+}
+
+// validateID 使用配置的校验器；自定义策略拒绝但 ID 本身合法时返回 ErrIDRejected
+func (s *UserService) validateID(id string) error {
+	if s.idValidator.Validate(id) {
+		return nil
+	}
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	return &IDError{ID: id, Err: ErrIDRejected}
+}
+
+// OnChange 注册变更回调；回调在变更完成且释放锁之后同步调用，因此可以安全地回调 UserService
+func (s *UserService) OnChange(fn func(evt ChangeEvent)) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	s.subscribers = append(s.subscribers, fn)
+}
+
+func (s *UserService) emit(evt ChangeEvent) {
+	switch evt.Type {
+	case ChangeCreated:
+		s.metrics.IncrCreate()
+	case ChangeUpdated, ChangeRestored, ChangeRenamed:
+		s.metrics.IncrUpdate()
+	case ChangeDeleted, ChangeExpired:
+		s.metrics.IncrDelete()
+	}
+
+	s.subMu.RLock()
+	subscribers := s.subscribers
+	s.subMu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(evt)
+	}
+}
+
+// FindByEmail 忽略首尾空白与大小写查找用户；多个用户共享邮箱时返回最近创建（或以该邮箱更新）的那个，
+// 只修改名字的更新不改变结果
+func (s *UserService) FindByEmail(email string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// 从最新加入索引的用户往前找，跳过已过期但尚未清理的用户
+	ids := s.byEmail[strings.ToLower(strings.TrimSpace(email))]
+	for i := len(ids) - 1; i >= 0; i-- {
+		user, err := s.store.Get(context.Background(), ids[i])
+		if err != nil {
+			return nil, err
+		}
+		if s.visible(user) {
+			return cloneUser(user), nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// checkEmailOwner 在启用 WithUniqueEmails 时确认邮箱未被 id 以外的用户使用，调用方需持有 s.mu 写锁
+func (s *UserService) checkEmailOwner(email, id string) error {
+	if !s.uniqueEmails {
+		return nil
+	}
+	for _, owner := range s.byEmail[strings.ToLower(email)] {
+		if owner == id {
+			continue
+		}
+		// 已过期的用户不再占用邮箱
+		if user, err := s.store.Get(context.Background(), owner); err == nil && !s.visible(user) {
+			continue
+		}
+		return &DuplicateEmailError{Email: email, ID: id, OwnerID: owner}
+	}
+	return nil
+}
+
+// index/unindex 同步维护邮箱与名字索引，调用方需持有 s.mu 写锁
+func (s *UserService) index(user *User) {
+	s.indexEmail(user)
+	s.names.Add(strings.ToLower(user.Name), user.ID)
+}
+
+func (s *UserService) unindex(user *User) {
+	s.unindexEmail(user)
+	s.names.Remove(strings.ToLower(user.Name), user.ID)
+}
+
+// reindex 只更新变化了的索引项；邮箱不变时保留该用户在 byEmail 中的位置，
+// 因此更新不会改变 FindByEmail 在共享邮箱的用户之间的选择
+func (s *UserService) reindex(previous, updated *User) {
+	if strings.ToLower(previous.Email) != strings.ToLower(updated.Email) {
+		s.unindexEmail(previous)
+		s.indexEmail(updated)
+	}
+	if oldName, newName := strings.ToLower(previous.Name), strings.ToLower(updated.Name); oldName != newName {
+		s.names.Remove(oldName, previous.ID)
+		s.names.Add(newName, updated.ID)
+	}
+}
+
+func (s *UserService) indexEmail(user *User) {
+	key := strings.ToLower(user.Email)
+	s.byEmail[key] = append(s.byEmail[key], user.ID)
+}
+
+func (s *UserService) unindexEmail(user *User) {
+	key := strings.ToLower(user.Email)
+	ids := s.byEmail[key]
+	for i, id := range ids {
+		if id == user.ID {
+			ids = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(s.byEmail, key)
+		return
+	}
+	s.byEmail[key] = ids
+}
+
+// SearchByNamePrefix 忽略大小写按名字前缀查找用户，返回按名字（同名按 ID）排序的副本
+// 空前缀匹配所有用户，即按名字排序的全量列表
+func (s *UserService) SearchByNamePrefix(prefix string) []*User {
+	ctx := context.Background()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := s.names.Prefix(strings.ToLower(prefix))
+	users := make([]*User, 0, len(ids))
+	for _, id := range ids {
+		user, err := s.store.Get(ctx, id)
+		if err != nil || !s.visible(user) {
+			continue
+		}
+		users = append(users, cloneUser(user))
+	}
+	return users
+}
+
+// NameIndex 将（已转小写的）用户名映射到 ID，供 SearchByNamePrefix 使用
+// UserService 在 s.mu 下调用，实现本身无需加锁
+type NameIndex interface {
+	Add(name, id string)
+	Remove(name, id string)
+	// Prefix 返回名字以 prefix 开头的 ID，按名字排序、同名按 ID 排序
+	Prefix(prefix string) []string
+}
+
+type nameEntry struct {
+	name string
+	id   string
+}
+
+// sortedNameIndex 是默认的 NameIndex：按 (name, id) 排序的切片
+// 查询为二分查找 + 顺序扫描匹配区间，写入需要移动元素，为 O(n)
+type sortedNameIndex struct {
+	entries []nameEntry
+}
+
+func newSortedNameIndex() *sortedNameIndex {
+	return &sortedNameIndex{}
+}
+
+// search 返回第一个不小于 (name, id) 的位置
+func (x *sortedNameIndex) search(name, id string) int {
+	return sort.Search(len(x.entries), func(i int) bool {
+		e := x.entries[i]
+		return e.name > name || (e.name == name && e.id >= id)
+	})
+}
+
+func (x *sortedNameIndex) Add(name, id string) {
+	i := x.search(name, id)
+	entry := nameEntry{name: name, id: id}
+	if i < len(x.entries) && x.entries[i] == entry {
+		return
+	}
+	x.entries = append(x.entries, nameEntry{})
+	copy(x.entries[i+1:], x.entries[i:])
+	x.entries[i] = entry
+}
+
+func (x *sortedNameIndex) Remove(name, id string) {
+	i := x.search(name, id)
+	if i < len(x.entries) && x.entries[i] == (nameEntry{name: name, id: id}) {
+		x.entries = append(x.entries[:i], x.entries[i+1:]...)
+	}
+}
+
+func (x *sortedNameIndex) Prefix(prefix string) []string {
+	start := sort.Search(len(x.entries), func(i int) bool {
+		return x.entries[i].name >= prefix
+	})
+	var ids []string
+	for _, e := range x.entries[start:] {
+		if !strings.HasPrefix(e.name, prefix) {
+			break
+		}
+		ids = append(ids, e.id)
+	}
+	return ids
+}
+
+// List 按 ID 升序返回 [offset, offset+limit) 窗口内用户的副本；limit <= 0 表示返回剩余全部，
+// offset 为负或超过过滤后的用户数时返回 ErrOffsetOutOfRange（等于用户数时返回空列表）
+// 默认跳过软删除的用户，offset/limit 作用于过滤后的结果
+func (s *UserService) List(offset, limit int, opts ...ReadOption) ([]*User, error) {
+	all, err := s.store.List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	includeDeleted := collectReadOptions(opts).includeDeleted
+	now := s.now()
+	kept := all[:0:0]
+	for _, user := range all {
+		if !expired(user, now) && (includeDeleted || live(user)) {
+			kept = append(kept, user)
+		}
+	}
+	all = kept
+	if offset < 0 || offset > len(all) {
+		return nil, ErrOffsetOutOfRange
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+
+	end := len(all)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	users := make([]*User, end-offset)
+	for i, user := range all[offset:end] {
+		users[i] = cloneUser(user)
+	}
+	return users, nil
+}
+
+// SortField 是 Query.OrderBy 可用的排序字段
+type SortField int
+
+const (
+	SortByID SortField = iota
+	SortByName
+	SortByEmail
+	SortByVersion
+)
+
+// compare 按字段比较两个用户；名字与邮箱忽略大小写
+func (f SortField) compare(a, b *User) int {
+	switch f {
+	case SortByName:
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case SortByEmail:
+		return strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+	case SortByVersion:
+		switch {
+		case a.Version < b.Version:
+			return -1
+		case a.Version > b.Version:
+			return 1
+		}
+		return 0
+	default:
+		return strings.Compare(a.ID, b.ID)
+	}
+}
+
+type queryOrder struct {
+	field SortField
+	asc   bool
+}
+
+// Query 是 UserService.Query 返回的查询构造器，各方法返回新的 Query，可以在一个基础查询上派生多个查询
+// 构造过程不访问 store，只有 Run 会短暂加读锁复制候选用户
+type Query struct {
+	service *UserService
+	preds   []func(*User) bool
+	orders  []queryOrder
+	offset  int
+	limit   int
+}
+
+// Query 开始一个查询；不加任何条件时 Run 等价于 List(0, 0)
+func (s *UserService) Query() Query {
+	return Query{service: s}
+}
+
+// Where 追加过滤条件，多个条件需同时满足；pred 在释放锁之后调用，收到的是副本，可以调用 UserService 的任意方法
+func (q Query) Where(pred func(*User) bool) Query {
+	q.preds = append(q.preds[:len(q.preds):len(q.preds)], pred)
+	return q
+}
+
+// OrderBy 追加排序字段，先追加的优先；所有字段都相同时按 ID 升序
+func (q Query) OrderBy(field SortField, asc bool) Query {
+	q.orders = append(q.orders[:len(q.orders):len(q.orders)], queryOrder{field: field, asc: asc})
+	return q
+}
+
+// Offset 跳过排序后的前 n 个结果，n <= 0 表示不跳过
+func (q Query) Offset(n int) Query {
+	q.offset = n
+	return q
+}
+
+// Limit 最多返回 n 个结果，n <= 0 表示不限制
+func (q Query) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+// Run 在读锁内复制软删除与已过期之外的用户，释放锁后再依次过滤、排序、截取，返回结果的副本
+// 过滤条件看到的是复制那一刻的数据，不会因为执行较慢而阻塞写操作
+func (q Query) Run() ([]*User, error) {
+	s := q.service
+
+	candidates, err := s.visibleUsers()
+	if err != nil {
+		return nil, err
+	}
+	matched := candidates[:0]
+	for _, user := range candidates {
+		snapshot := *user
+		if q.matches(&snapshot) {
+			matched = append(matched, user)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		for _, order := range q.orders {
+			c := order.field.compare(matched[i], matched[j])
+			if c == 0 {
+				continue
+			}
+			return (c < 0) == order.asc
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	if q.offset >= len(matched) {
+		matched = nil
+	} else if q.offset > 0 {
+		matched = matched[q.offset:]
+	}
+	if q.limit > 0 && q.limit < len(matched) {
+		matched = matched[:q.limit]
+	}
+
+	return matched, nil
+}
+
+// visibleUsers 在读锁内复制所有未删除且未过期的用户，返回的副本归调用方所有
+func (s *UserService) visibleUsers() ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all, err := s.store.List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	users := make([]*User, 0, len(all))
+	for _, user := range all {
+		if live(user) && !expired(user, now) {
+			users = append(users, cloneUser(user))
+		}
+	}
+	return users, nil
+}
+
+func (q Query) matches(user *User) bool {
+	for _, pred := range q.preds {
+		if !pred(user) {
+			return false
+		}
+	}
+	return true
+}
+
+// ForEach 遍历所有用户（不保证顺序），fn 返回 false 时提前停止；fn 收到的是副本
+// 先在读锁内复制可见用户，释放锁后再逐个调用 fn，因此 fn 可以调用 UserService 的任意方法，
+// 遍历期间的写入不会出现在本次遍历中
+func (s *UserService) ForEach(fn func(*User) bool) {
+	for _, user := range s.snapshotVisible() {
+		if !fn(user) {
+			return
+		}
+	}
+}
+
+// snapshotVisible 在读锁内复制所有可见用户；store 实现 Ranger 时逐个遍历，不额外构造全量列表
+// store 出错时返回 nil
+func (s *UserService) snapshotVisible() []*User {
+	r, ok := s.store.(Ranger)
+	if !ok {
+		users, _ := s.visibleUsers()
+		return users
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []*User
+	r.Range(context.Background(), func(user *User) bool {
+		if s.visible(user) {
+			users = append(users, cloneUser(user))
+		}
+		return true
+	})
+	return users
+}
+
+// Snapshot 返回按 ID 排序的全量副本，调用方可以在不持有任何锁的情况下遍历
+func (s *UserService) Snapshot() []User {
+	s.mu.RLock()
+	users, err := s.store.List(context.Background())
+	s.mu.RUnlock()
+	if err != nil {
+		return nil
+	}
+
+	snapshot := make([]User, 0, len(users))
+	for _, user := range users {
+		if s.visible(user) {
+			snapshot = append(snapshot, *user)
+		}
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].ID < snapshot[j].ID })
+	return snapshot
+}
+
+// Exists 判断用户是否存在（软删除与已过期的用户视为不存在）；store 出错时返回 false
+func (s *UserService) Exists(id string) bool {
+	ctx := context.Background()
+	if e, ok := s.store.(Exister); ok && !s.softDelete && !s.hasTTL.Load() {
+		found, err := e.Exists(ctx, id)
+		return err == nil && found
+	}
+	user, err := s.store.Get(ctx, id)
+	return err == nil && s.visible(user)
+}
+
+// Count 返回未删除的用户总数；每次都在锁内读取 store，不缓存计数，store 出错时返回 0
+// 软删除模式或写入过带 TTL 的用户后需要遍历以排除墓碑与过期用户，为 O(n)
+func (s *UserService) Count() int {
+	if !s.softDelete && !s.hasTTL.Load() {
+		n, err := s.store.Len(context.Background())
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+	n := 0
+	s.ForEach(func(*User) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ReadOnlyUserService 是 UserService 的只读子集，供只需读取用户的调用方作为依赖声明
+// *UserService 本身也满足该接口
+type ReadOnlyUserService interface {
+	GetUser(id string, opts ...ReadOption) (*User, error)
+	List(offset, limit int, opts ...ReadOption) ([]*User, error)
+	Count() int
+	Exists(id string) bool
+	FindByEmail(email string) (*User, error)
+}
+
+var _ ReadOnlyUserService = (*UserService)(nil)
+
+// readOnlyView 只转发读方法；service 字段不导出，调用方无法通过类型断言取回可写的 UserService
+type readOnlyView struct {
+	service *UserService
+}
+
+// ReadOnly 返回与 s 共享同一 store 的只读视图，读取始终看到最新数据
+func (s *UserService) ReadOnly() ReadOnlyUserService {
+	return readOnlyView{service: s}
+}
+
+func (v readOnlyView) GetUser(id string, opts ...ReadOption) (*User, error) {
+	return v.service.GetUser(id, opts...)
+}
+
+func (v readOnlyView) List(offset, limit int, opts ...ReadOption) ([]*User, error) {
+	return v.service.List(offset, limit, opts...)
+}
+
+func (v readOnlyView) Count() int {
+	return v.service.Count()
+}
+
+func (v readOnlyView) Exists(id string) bool {
+	return v.service.Exists(id)
+}
+
+func (v readOnlyView) FindByEmail(email string) (*User, error) {
+	return v.service.FindByEmail(email)
+}
+
+// EmailPattern 是默认校验器使用的正则，支持的是 RFC 5321/5322 的一个常用子集：
+//   - 本地部分为 dot-atom：字母、数字与 !#$%&'*+/=?^_`{|}~- ，点号不能在首尾或连续出现；
+//     因此支持 `user+tag@example.com`，不支持带引号的本地部分（`"a b"@example.com`）与注释
+//   - 域名为至少两级的 ASCII 主机名：每级 1-63 个字母、数字或连字符，不以连字符开头或结尾；
+//     顶级域至少两个字符且以字母开头（允许 punycode 的 `xn--`），不支持 IP 字面量（`user@[10.0.0.1]`）
+//
+// 长度限制（本地部分 64 字节、整体 254 字节）与首尾空白的处理见 ValidateEmailDetailed
+const EmailPattern = "^[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+(?:\\.[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+)*" +
+	"@(?:[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?\\.)+[A-Za-z](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])$"
+
+var emailRe = regexp.MustCompile(EmailPattern)
+
+// EmailValidator 是可插拔的邮箱校验策略
+type EmailValidator interface {
+	Validate(email string) bool
+}
+
+// EmailValidatorFunc 让普通函数满足 EmailValidator
+type EmailValidatorFunc func(email string) bool
+
+func (f EmailValidatorFunc) Validate(email string) bool {
+	return f(email)
+}
+
+var DefaultEmailValidator EmailValidator = EmailValidatorFunc(ValidateEmail)
+
+var (
+	ErrEmailEmpty            = errors.New("email is empty")
+	ErrEmailWhitespace       = errors.New("email contains whitespace")
+	ErrEmailMissingAt        = errors.New("email is missing @")
+	ErrEmailMissingDomainDot = errors.New("email domain is missing a dot")
+	ErrEmailConsecutiveDots  = errors.New("email contains consecutive dots")
+	ErrEmailTooLong          = errors.New("email is too long")
+	ErrEmailMalformed        = errors.New("email is malformed")
+	ErrEmailRejected         = errors.New("email rejected by validator")
+)
+
+// EmailError 描述邮箱校验失败的具体原因；errors.Is 既能匹配原因也能匹配 ErrInvalidEmail
+type EmailError struct {
+	Email string
+	Err   error
+}
+
+func (e *EmailError) Error() string {
+	return fmt.Sprintf("invalid email %q: %v", e.Email, e.Err)
+}
+
+func (e *EmailError) Unwrap() error {
+	return e.Err
+}
+
+func (e *EmailError) Is(target error) bool {
+	return target == ErrInvalidEmail
+}
+
+// IDValidator 是可插拔的用户 ID 校验策略
+type IDValidator interface {
+	Validate(id string) bool
+}
+
+// IDValidatorFunc 让普通函数满足 IDValidator
+type IDValidatorFunc func(id string) bool
+
+func (f IDValidatorFunc) Validate(id string) bool {
+	return f(id)
+}
+
+// DefaultIDValidator 只拒绝空 ID 与纯空白 ID
+var DefaultIDValidator IDValidator = IDValidatorFunc(func(id string) bool {
+	return ValidateID(id) == nil
+})
+
+var (
+	ErrIDBlank    = errors.New("user id contains only whitespace")
+	ErrIDRejected = errors.New("user id rejected by validator")
+)
+
+// IDError 描述 ID 校验失败的具体原因；errors.Is 既能匹配原因也能匹配 ErrInvalidID
+type IDError struct {
+	ID  string
+	Err error
+}
+
+func (e *IDError) Error() string {
+	return fmt.Sprintf("invalid user id %q: %v", e.ID, e.Err)
+}
+
+func (e *IDError) Unwrap() error {
+	return e.Err
+}
+
+func (e *IDError) Is(target error) bool {
+	return target == ErrInvalidID
+}
+
+// ValidateID 执行默认的 ID 校验，失败时返回 *IDError（原因为 ErrEmptyID 或 ErrIDBlank）
+func ValidateID(id string) error {
+	switch {
+	case id == "":
+		return &IDError{ID: id, Err: ErrEmptyID}
+	case strings.TrimSpace(id) == "":
+		return &IDError{ID: id, Err: ErrIDBlank}
+	}
+	return nil
+}
+
+// BatchMode 决定批量写入遇到非法记录时的行为
+type BatchMode int
+
+const (
+	// AllOrNothing 任一记录非法则整批不写入
+	AllOrNothing BatchMode = iota
+	// BestEffort 写入所有合法记录，并报告非法记录
+	BestEffort
+)
+
+// RecordError 描述批量操作中某条记录的失败原因，Index 为其在输入中的下标
+type RecordError struct {
+	Index int
+	ID    string
+	Err   error
+}
+
+func (e RecordError) Error() string {
+	return fmt.Sprintf("record %d (id %q): %v", e.Index, e.ID, e.Err)
+}
+
+func (e RecordError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError 汇总批量操作中的所有记录错误
+type BatchError struct {
+	Records []RecordError
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Records) == 1 {
+		return e.Records[0].Error()
+	}
+	return fmt.Sprintf("%d invalid records, first: %v", len(e.Records), e.Records[0])
+}
+
+// Unwrap 返回每条记录的错误，使 errors.Is/As 可以匹配其中任意一条的原因（如 ErrDuplicateEmail）
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Records))
+	for i, rec := range e.Records {
+		errs[i] = rec
+	}
+	return errs
+}
+
+// MarshalJSON 导出全部未删除的用户，按 ID 排序以保证输出稳定
+func (s *UserService) MarshalJSON() ([]byte, error) {
+	users, err := s.List(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(users)
+}
+
+// LoadJSON 导入 MarshalJSON 产生的快照，同 ID 的已有用户会被覆盖
+// 导入记录保留快照中的 Version；已有用户（含墓碑）的版本不小于它时改为现有版本加一，保证版本只增不减。
+// 快照中的 ExpiresAt 同样保留，使往返不丢失 TTL
+func (s *UserService) LoadJSON(data []byte, mode BatchMode) error {
+	var users []*User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return err
+	}
+
+	result, err := s.createUsers(users, mode, true)
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return &BatchError{Records: result.Errors}
+	}
+	return nil
+}
+
+// BatchResult 是 CreateUsers 的结果，Errors 按输入下标排序
+type BatchResult struct {
+	Succeeded int
+	Errors    []RecordError
+}
+
+type batchEntry struct {
+	index int
+	user  *User
+}
+
+// CreateUsers 在一次写锁内批量写入用户
+// 所有记录先完成校验再写入；AllOrNothing 模式下存在非法记录、邮箱冲突或 store 写入失败时整批回滚，
+// 返回的错误总是 *BatchError，其中的 RecordError 指出失败记录的下标
+// 索引随每条记录即时更新，因此 WithUniqueEmails 同样拒绝批次内两个 ID 使用同一邮箱
+// 与 CreateUser 一样忽略记录中的 Version 与 ExpiresAt：版本由服务端重新分配，写入的用户不过期
+func (s *UserService) CreateUsers(users []*User, mode BatchMode) (BatchResult, error) {
+	return s.createUsers(users, mode, false)
+}
+
+// createUsers 是 CreateUsers/LoadJSON 的共同实现；imported 为 true 时保留记录中的 ExpiresAt，
+// 并以其中的 Version 作为版本下限
+func (s *UserService) createUsers(users []*User, mode BatchMode, imported bool) (BatchResult, error) {
+	valid, failed := s.checkBatch(users, imported)
+	result := BatchResult{Errors: failed}
+	if len(failed) > 0 && mode == AllOrNothing {
+		return result, &BatchError{Records: failed}
+	}
+
+	type applied struct {
+		user     *User
+		previous *User
+		replaced bool // 覆盖的是可见用户（非墓碑、未过期）
+	}
+	ctx := context.Background()
+	done := make([]applied, 0, len(valid))
+
+	s.lock()
+	for _, entry := range valid {
+		var (
+			previous   *User
+			minVersion uint64
+		)
+		if imported {
+			minVersion = entry.user.Version
+		}
+		if entry.user.ExpiresAt != nil {
+			// 在写入之前置位，Count/Exists 不会走跳过过期检查的快速路径
+			s.hasTTL.Store(true)
+		}
+		err := s.checkEmailOwner(entry.user.Email, entry.user.ID)
+		if err == nil {
+			previous, err = s.putVersioned(ctx, entry.user, minVersion)
+		}
+		if err != nil {
+			rec := RecordError{Index: entry.index, ID: entry.user.ID, Err: err}
+			if mode == AllOrNothing {
+				for i := len(done) - 1; i >= 0; i-- {
+					s.unindex(done[i].user)
+					if done[i].previous != nil {
+						s.store.Put(ctx, done[i].previous)
+						if live(done[i].previous) {
+							s.index(done[i].previous)
+						}
+					} else {
+						s.store.Delete(ctx, done[i].user.ID)
+					}
+				}
+				s.unlock()
+				result.Errors = append(result.Errors, rec)
+				return result, &BatchError{Records: result.Errors}
+			}
+			result.Errors = append(result.Errors, rec)
+			continue
+		}
+		if live(previous) {
+			s.unindex(previous)
+		}
+		s.index(entry.user)
+		done = append(done, applied{user: entry.user, previous: previous, replaced: s.visible(previous)})
+	}
+	s.unlock()
+
+	// 只有确实写入了会过期的记录才需要 janitor
+	for _, a := range done {
+		if a.user.ExpiresAt != nil {
+			s.startJanitor()
+			break
+		}
+	}
+
+	events := make([]ChangeEvent, 0, len(done))
+	for _, a := range done {
+		evt := ChangeEvent{Type: ChangeCreated, ID: a.user.ID, User: *a.user}
+		if a.replaced {
+			evt.Type = ChangeUpdated
+		}
+		events = append(events, evt)
+	}
+
+	for _, evt := range events {
+		s.emit(evt)
+	}
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Index < result.Errors[j].Index })
+	result.Succeeded = len(done)
+	return result, nil
+}
+
+// checkBatch 执行与 CreateUser 相同的校验，并拒绝批次内重复的 ID；imported 为 false 时清除 ExpiresAt
+func (s *UserService) checkBatch(users []*User, imported bool) ([]batchEntry, []RecordError) {
+	var failed []RecordError
+	valid := make([]batchEntry, 0, len(users))
+	seen := make(map[string]bool, len(users))
+	for i, user := range users {
+		var (
+			email string
+			err   error
+		)
+		switch {
+		case user == nil:
+			err = ErrNilUser
+		case seen[user.ID]:
+			err = ErrDuplicateID
+		default:
+			if err = s.validateID(user.ID); err == nil {
+				email, err = s.prepareEmail(user.Email)
+			}
+		}
+		if err != nil {
+			rec := RecordError{Index: i, Err: err}
+			if user != nil {
+				rec.ID = user.ID
+			}
+			failed = append(failed, rec)
+			continue
+		}
+		seen[user.ID] = true
+		normalized := *user
+		normalized.Email = email
+		normalized.DeletedAt = nil
+		if !imported {
+			normalized.ExpiresAt = nil
+		}
+		valid = append(valid, batchEntry{index: i, user: &normalized})
+	}
+	return valid, failed
+}
+
+// ValidateUsers 对 users 执行与 CreateUsers 相同的全部检查但不写入任何数据，返回按下标排序的全部问题
+// 结果与 CreateUsers(users, BestEffort) 的 Errors 一致（store 写入失败除外）：记录按顺序检查，
+// 邮箱冲突同时考虑 store 中的现有用户与本批排在前面的合法记录；返回空时 AllOrNothing 导入不会因校验失败
+func (s *UserService) ValidateUsers(users []*User) []RecordError {
+	valid, failed := s.checkBatch(users, false)
+	if !s.uniqueEmails {
+		return failed
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pending := pendingEmails{
+		written: make(map[string]*User, len(valid)),
+		claimed: make(map[string][]string),
+	}
+	for _, entry := range valid {
+		if err := s.checkPendingEmailOwner(entry.user, pending); err != nil {
+			failed = append(failed, RecordError{Index: entry.index, ID: entry.user.ID, Err: err})
+			continue
+		}
+		pending.written[entry.user.ID] = entry.user
+		key := strings.ToLower(entry.user.Email)
+		pending.claimed[key] = append(pending.claimed[key], entry.user.ID)
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Index < failed[j].Index })
+	return failed
+}
+
+// pendingEmails 记录 ValidateUsers 假想写入的用户，模拟 CreateUsers 逐条更新邮箱索引的效果
+type pendingEmails struct {
+	written map[string]*User    // ID → 本批已通过检查的记录
+	claimed map[string][]string // 小写邮箱 → 本批使用该邮箱的 ID
+}
+
+// checkPendingEmailOwner 是 checkEmailOwner 的只读版本：被本批覆盖的 ID 不再占用 store 中的旧邮箱
+// 调用方需持有 s.mu 读锁
+func (s *UserService) checkPendingEmailOwner(user *User, pending pendingEmails) error {
+	key := strings.ToLower(user.Email)
+	for _, owner := range s.byEmail[key] {
+		if _, overwritten := pending.written[owner]; overwritten || owner == user.ID {
+			continue
+		}
+		if existing, err := s.store.Get(context.Background(), owner); err == nil && !s.visible(existing) {
+			continue
+		}
+		return &DuplicateEmailError{Email: user.Email, ID: user.ID, OwnerID: owner}
+	}
+	for _, owner := range pending.claimed[key] {
+		if owner != user.ID && s.visible(pending.written[owner]) {
+			return &DuplicateEmailError{Email: user.Email, ID: user.ID, OwnerID: owner}
+		}
+	}
+	return nil
+}
+
+// NormalizeEmail 去除首尾空白并将域名转为小写，本地部分保持原样
+func NormalizeEmail(email string) (string, error) {
+	return normalizeEmail(email, false)
+}
+
+func normalizeEmail(email string, foldLocal bool) (string, error) {
+	trimmed := strings.TrimSpace(email)
+	if trimmed == "" {
+		return "", &EmailError{Email: email, Err: ErrEmailEmpty}
+	}
+	at := strings.LastIndex(trimmed, "@")
+	if at < 0 {
+		return "", &EmailError{Email: email, Err: ErrEmailMissingAt}
+	}
+	local := trimmed[:at]
+	if foldLocal {
+		local = strings.ToLower(local)
+	}
+	return local + "@" + strings.ToLower(trimmed[at+1:]), nil
+}
+
+// ValidateEmail 判断邮箱是否符合 EmailPattern 描述的子集，首尾空白会先被去除
+func ValidateEmail(email string) bool {
+	return ValidateEmailDetailed(email) == nil
+}
+
+// ValidateEmailDetailed 与 ValidateEmail 规则相同，失败时返回带具体原因的 *EmailError
+// 首尾空白被忽略，内部空白返回 ErrEmailWhitespace；本地部分超过 64 字节或整体超过 254 字节返回 ErrEmailTooLong
+func ValidateEmailDetailed(email string) error {
+	trimmed := strings.TrimSpace(email)
+	at := strings.LastIndex(trimmed, "@")
+
+	var reason error
+	switch {
+	case trimmed == "":
+		reason = ErrEmailEmpty
+	case strings.IndexFunc(trimmed, unicode.IsSpace) >= 0:
+		reason = ErrEmailWhitespace
+	case at < 0:
+		reason = ErrEmailMissingAt
+	case !strings.Contains(trimmed[at+1:], "."):
+		reason = ErrEmailMissingDomainDot
+	case strings.Contains(trimmed, ".."):
+		reason = ErrEmailConsecutiveDots
+	case at > 64 || len(trimmed) > 254:
+		reason = ErrEmailTooLong
+	case !emailRe.MatchString(trimmed):
+		reason = ErrEmailMalformed
+	default:
+		return nil
+	}
+	return &EmailError{Email: email, Err: reason}
+}
@@ -0,0 +1,125 @@
+package userservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const benchUsers = 4096
+
+// seedUsers 写入 n 个用户并返回它们的 ID
+func seedUsers(tb testing.TB, s *UserService, n int) []string {
+	tb.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("user-%d", i)
+		user := &User{ID: ids[i], Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+		if _, err := s.CreateUser(user); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return ids
+}
+
+// BenchmarkMixedReadWrite 对比单分片（等价于原先的单把 RWMutex）与默认 32 分片在 90% 读、10% 写负载下的吞吐
+func BenchmarkMixedReadWrite(b *testing.B) {
+	for _, shards := range []int{1, defaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			s := NewUserServiceSharded(shards)
+			ids := seedUsers(b, s, benchUsers)
+			var seq atomic.Uint64
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := seq.Add(1)
+					id := ids[n%benchUsers]
+					if n%10 == 0 {
+						s.CreateUser(&User{ID: id, Name: "Updated", Email: "updated@example.com"})
+						continue
+					}
+					if _, err := s.GetUser(id); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// scanNameIndex 是线性扫描的 NameIndex，作为 sortedNameIndex 的对照
+type scanNameIndex struct {
+	names map[string]string // ID → 名字
+}
+
+func (x *scanNameIndex) Add(name, id string) { x.names[id] = name }
+
+func (x *scanNameIndex) Remove(name, id string) { delete(x.names, id) }
+
+func (x *scanNameIndex) Prefix(prefix string) []string {
+	var entries []nameEntry
+	for id, name := range x.names {
+		if strings.HasPrefix(name, prefix) {
+			entries = append(entries, nameEntry{name: name, id: id})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].name != entries[j].name {
+			return entries[i].name < entries[j].name
+		}
+		return entries[i].id < entries[j].id
+	})
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.id
+	}
+	return ids
+}
+
+var nameIndexes = []struct {
+	name string
+	new  func() NameIndex
+}{
+	{"scan", func() NameIndex { return &scanNameIndex{names: make(map[string]string)} }},
+	{"sorted", func() NameIndex { return newSortedNameIndex() }},
+}
+
+// BenchmarkSearchByNamePrefix 对比线性扫描与默认有序索引：前缀 "user 12" 在 4096 个用户中命中约 110 个
+func BenchmarkSearchByNamePrefix(b *testing.B) {
+	for _, idx := range nameIndexes {
+		b.Run(idx.name, func(b *testing.B) {
+			s := NewUserService(WithNameIndex(idx.new()))
+			seedUsers(b, s, benchUsers)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if len(s.SearchByNamePrefix("User 12")) == 0 {
+					b.Fatal("no matches")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCreateUserNameIndex 衡量维护名字索引给写入带来的开销
+func BenchmarkCreateUserNameIndex(b *testing.B) {
+	for _, idx := range nameIndexes {
+		b.Run(idx.name, func(b *testing.B) {
+			s := NewUserService(WithNameIndex(idx.new()))
+			seedUsers(b, s, benchUsers)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				id := fmt.Sprintf("bench-%d", i)
+				s.CreateUser(&User{ID: id, Name: "Bench " + id, Email: "bench@example.com"})
+			}
+		})
+	}
+}
@@ -0,0 +1,1198 @@
+package userservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ids 提取用户 ID，保持顺序
+func ids(users []*User) []string {
+	out := make([]string, len(users))
+	for i, user := range users {
+		out[i] = user.ID
+	}
+	return out
+}
+
+// mustCreate 写入用户，失败时终止测试
+func mustCreate(t *testing.T, s *UserService, id, name, email string) {
+	t.Helper()
+	if _, err := s.CreateUser(&User{ID: id, Name: name, Email: email}); err != nil {
+		t.Fatalf("CreateUser(%q): %v", id, err)
+	}
+}
+
+func TestGetUserReturnsCopy(t *testing.T) {
+	s := NewUserService()
+	mustCreate(t, s, "u1", "Alice", "alice@example.com")
+
+	user, err := s.GetUser("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Email = "x"
+	user.Name = "Mallory"
+
+	again, err := s.GetUser("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Email != "alice@example.com" || again.Name != "Alice" {
+		t.Fatalf("mutating the returned user changed the store: %+v", again)
+	}
+	if found, err := s.FindByEmail("alice@example.com"); err != nil || found.ID != "u1" {
+		t.Fatalf("FindByEmail after mutation = %+v, %v", found, err)
+	}
+
+	// List 返回的同样是副本
+	list, err := s.List(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list[0].Name = "Eve"
+	if again, _ := s.GetUser("u1"); again.Name != "Alice" {
+		t.Fatalf("mutating a listed user changed the store: %+v", again)
+	}
+
+	// GetUserRef 返回内部指针，与 GetUser 的副本不同
+	ref, err := s.GetUserRef("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref == again {
+		t.Fatal("GetUser returned the internal pointer")
+	}
+}
+
+func TestListOffsetOutOfRange(t *testing.T) {
+	s := NewUserService(WithSoftDelete())
+	for _, id := range []string{"u1", "u2", "u3"} {
+		mustCreate(t, s, id, "User", id+"@example.com")
+	}
+	s.DeleteUser("u3")
+
+	for _, offset := range []int{-1, 3} {
+		if users, err := s.List(offset, 0); !errors.Is(err, ErrOffsetOutOfRange) || users != nil {
+			t.Fatalf("List(%d, 0) = %v, %v, want ErrOffsetOutOfRange", offset, ids(users), err)
+		}
+	}
+	// offset 作用于过滤后的结果：等于可见用户数时为空列表，包含墓碑时范围随之变大
+	if users, err := s.List(2, 0); err != nil || len(users) != 0 {
+		t.Fatalf("List(2, 0) = %v, %v", ids(users), err)
+	}
+	if users, err := s.List(2, 1, IncludeDeleted()); err != nil || !slices.Equal(ids(users), []string{"u3"}) {
+		t.Fatalf("List(2, 1, IncludeDeleted) = %v, %v", ids(users), err)
+	}
+}
+
+// 共享邮箱时返回最近创建的用户；只改名字的更新不改变顺序，改邮箱视为以新邮箱重新加入
+func TestFindByEmailPrefersLatestCreated(t *testing.T) {
+	s := NewUserService()
+	mustCreate(t, s, "a", "Ann", "x@example.com")
+	mustCreate(t, s, "b", "Bob", "X@example.com")
+
+	name := "Annie"
+	if _, err := s.UpdateUser("a", UserPatch{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"x@example.com", " x@example.com ", "\tX@EXAMPLE.COM\n"} {
+		if found, err := s.FindByEmail(email); err != nil || found.ID != "b" {
+			t.Fatalf("FindByEmail(%q) = %+v, %v, want b", email, found, err)
+		}
+	}
+	if users := s.SearchByNamePrefix("annie"); !slices.Equal(ids(users), []string{"a"}) {
+		t.Fatalf("name index after update = %v", ids(users))
+	}
+
+	email := "x@example.com"
+	if _, err := s.UpdateUser("a", UserPatch{Email: &email}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := s.FindByEmail("x@example.com"); err != nil || found.ID != "b" {
+		t.Fatalf("FindByEmail after same-email update = %+v, %v, want b", found, err)
+	}
+
+	other := "y@example.com"
+	if _, err := s.UpdateUser("b", UserPatch{Email: &other}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := s.FindByEmail("x@example.com"); err != nil || found.ID != "a" {
+		t.Fatalf("FindByEmail after b moved away = %+v, %v, want a", found, err)
+	}
+	if found, err := s.FindByEmail("y@example.com"); err != nil || found.ID != "b" {
+		t.Fatalf("FindByEmail(new email) = %+v, %v, want b", found, err)
+	}
+}
+
+func TestSearchByNamePrefix(t *testing.T) {
+	for _, idx := range nameIndexes {
+		t.Run(idx.name, func(t *testing.T) {
+			s := NewUserService(WithNameIndex(idx.new()))
+			mustCreate(t, s, "u1", "alice", "a@example.com")
+			mustCreate(t, s, "u2", "Albert", "b@example.com")
+			mustCreate(t, s, "u3", "Bob", "c@example.com")
+			mustCreate(t, s, "u4", "ALICE", "d@example.com")
+
+			// 忽略大小写，按名字排序，同名按 ID 排序
+			if got := ids(s.SearchByNamePrefix("AL")); !slices.Equal(got, []string{"u2", "u1", "u4"}) {
+				t.Fatalf("prefix AL = %v", got)
+			}
+			if got := ids(s.SearchByNamePrefix("")); !slices.Equal(got, []string{"u2", "u1", "u4", "u3"}) {
+				t.Fatalf("empty prefix = %v", got)
+			}
+
+			// 索引随更新与删除同步
+			name := "Carol"
+			if _, err := s.UpdateUser("u1", UserPatch{Name: &name}); err != nil {
+				t.Fatal(err)
+			}
+			s.DeleteUser("u2")
+			if got := ids(s.SearchByNamePrefix("al")); !slices.Equal(got, []string{"u4"}) {
+				t.Fatalf("prefix al after update = %v", got)
+			}
+			if got := ids(s.SearchByNamePrefix("c")); !slices.Equal(got, []string{"u1"}) {
+				t.Fatalf("prefix c after update = %v", got)
+			}
+		})
+	}
+}
+
+func TestCompareAndUpdateConcurrentConflict(t *testing.T) {
+	const writers = 16
+	s := NewUserService()
+	mustCreate(t, s, "u1", "Alice", "alice@example.com")
+	user, _ := s.GetUser("u1")
+
+	// 所有写者基于同一版本竞争，只有一个能成功
+	var (
+		wg        sync.WaitGroup
+		start     = make(chan struct{})
+		mu        sync.Mutex
+		succeeded []string
+		conflicts int
+	)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			<-start
+			_, err := s.CompareAndUpdate("u1", user.Version, UserPatch{Name: &name})
+			mu.Lock()
+			defer mu.Unlock()
+			var conflict *VersionConflictError
+			switch {
+			case err == nil:
+				succeeded = append(succeeded, name)
+			case errors.As(err, &conflict) && errors.Is(err, ErrVersionConflict):
+				if conflict.Expected != user.Version || conflict.Actual != user.Version+1 {
+					t.Errorf("conflict = %+v", conflict)
+				}
+				conflicts++
+			default:
+				t.Errorf("CompareAndUpdate: %v", err)
+			}
+		}(fmt.Sprintf("writer-%d", i))
+	}
+	close(start)
+	wg.Wait()
+
+	if len(succeeded) != 1 || conflicts != writers-1 {
+		t.Fatalf("succeeded %v, conflicts %d", succeeded, conflicts)
+	}
+	got, _ := s.GetUser("u1")
+	if got.Name != succeeded[0] || got.Version != user.Version+1 {
+		t.Fatalf("stored %+v, winner %s", got, succeeded[0])
+	}
+}
+
+func TestCompareAndUpdateRetryLoopLosesNoUpdates(t *testing.T) {
+	const writers, rounds = 8, 25
+	s := NewUserService()
+	mustCreate(t, s, "u1", "0", "alice@example.com")
+
+	// 读取-修改-条件写入的重试循环：每次成功的写入都恰好使版本加一
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				for {
+					user, err := s.GetUser("u1")
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					var n int
+					fmt.Sscan(user.Name, &n)
+					name := fmt.Sprint(n + 1)
+					_, err = s.CompareAndUpdate("u1", user.Version, UserPatch{Name: &name})
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, ErrVersionConflict) {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _ := s.GetUser("u1")
+	if got.Name != fmt.Sprint(writers*rounds) || got.Version != uint64(writers*rounds+1) {
+		t.Fatalf("after %d increments: %+v", writers*rounds, got)
+	}
+}
+
+// 导入保留快照中的版本，使导出前拿到的版本在导入后仍可用于 CompareAndUpdate；版本只增不减
+func TestLoadJSONKeepsVersions(t *testing.T) {
+	source := NewUserService()
+	mustCreate(t, source, "u1", "Alice", "alice@example.com")
+	mustCreate(t, source, "u2", "Bob", "bob@example.com")
+	for _, name := range []string{"Alicia", "Ally"} {
+		if _, err := source.UpdateUser("u1", UserPatch{Name: &name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := source.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewUserService()
+	mustCreate(t, s, "u2", "Bob", "bob@example.com")
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprint("Bob ", i)
+		if _, err := s.UpdateUser("u2", UserPatch{Name: &name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.LoadJSON(data, AllOrNothing); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.GetUser("u1"); user.Version != 3 || user.Name != "Ally" {
+		t.Fatalf("imported u1 = %+v, want version 3", user)
+	}
+	// 已有的 u2 版本为 5，高于快照中的 1
+	if user, _ := s.GetUser("u2"); user.Version != 6 || user.Name != "Bob" {
+		t.Fatalf("imported u2 = %+v, want version 6", user)
+	}
+	name := "Al"
+	if _, err := s.CompareAndUpdate("u1", 3, UserPatch{Name: &name}); err != nil {
+		t.Fatalf("CompareAndUpdate with the exported version: %v", err)
+	}
+
+	// CreateUsers 仍由服务端分配版本
+	if _, err := s.CreateUsers([]*User{{ID: "u3", Name: "Cid", Email: "cid@example.com", Version: 42}}, AllOrNothing); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.GetUser("u3"); user.Version != 1 {
+		t.Fatalf("CreateUsers kept the caller's version: %+v", user)
+	}
+}
+
+// 只有 LoadJSON 保留记录中的 ExpiresAt；janitor 只在确实写入了会过期的记录后启动
+func TestCreateUsersIgnoresExpiresAtAndLoadJSONKeepsIt(t *testing.T) {
+	s := NewUserService(WithUniqueEmails(true))
+	defer s.Close()
+	expiresAt := time.Now().Add(time.Hour)
+
+	if _, err := s.CreateUsers([]*User{{ID: "u1", Name: "Ann", Email: "ann@example.com", ExpiresAt: &expiresAt}}, AllOrNothing); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.GetUser("u1"); user.ExpiresAt != nil {
+		t.Fatalf("CreateUsers kept the caller's ExpiresAt: %+v", user)
+	}
+	if s.janitorDone != nil {
+		t.Fatal("janitor started without any expiring user")
+	}
+
+	// 整批被拒绝时什么都没写入，也不启动 janitor
+	rejected, _ := json.Marshal([]*User{
+		{ID: "u2", Name: "Bob", Email: "bob@example.com", ExpiresAt: &expiresAt},
+		{ID: "u3", Name: "Cid", Email: "ann@example.com"},
+	})
+	var batch *BatchError
+	if err := s.LoadJSON(rejected, AllOrNothing); !errors.As(err, &batch) {
+		t.Fatalf("LoadJSON = %v, want *BatchError", err)
+	}
+	if s.janitorDone != nil || s.Exists("u2") {
+		t.Fatal("rejected batch wrote data or started the janitor")
+	}
+
+	data, _ := json.Marshal([]*User{{ID: "u2", Name: "Bob", Email: "bob@example.com", ExpiresAt: &expiresAt}})
+	if err := s.LoadJSON(data, AllOrNothing); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.GetUser("u2"); user.ExpiresAt == nil || !user.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("LoadJSON dropped ExpiresAt: %+v", user)
+	}
+	if s.janitorDone == nil {
+		t.Fatal("janitor not started after importing an expiring user")
+	}
+}
+
+// failingStore 在写入 failID 时返回 errStoreDown，其余操作交给 MemoryStore
+type failingStore struct {
+	*MemoryStore
+	failID string
+}
+
+var errStoreDown = errors.New("store down")
+
+func (f *failingStore) Put(ctx context.Context, user *User) (*User, error) {
+	if user.ID == f.failID {
+		return nil, errStoreDown
+	}
+	return f.MemoryStore.Put(ctx, user)
+}
+
+// AllOrNothing 下写入阶段的失败（邮箱冲突、store 出错）同样以 *BatchError 返回并指出失败记录的下标
+func TestCreateUsersAllOrNothingReportsFailingRecord(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore(), failID: "broken"}
+	s, err := NewUserServiceWithStore(context.Background(), store, WithUniqueEmails(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, s, "u0", "Ann", "ann@example.com")
+
+	tests := []struct {
+		name  string
+		users []*User
+		index int
+		cause error
+	}{
+		{
+			"duplicate email",
+			[]*User{
+				{ID: "u1", Name: "Bob", Email: "bob@example.com"},
+				{ID: "u2", Name: "Cid", Email: "ann@example.com"},
+			},
+			1, ErrDuplicateEmail,
+		},
+		{
+			"store error",
+			[]*User{
+				{ID: "u1", Name: "Bob", Email: "bob@example.com"},
+				{ID: "u2", Name: "Cid", Email: "cid@example.com"},
+				{ID: "broken", Name: "Dee", Email: "dee@example.com"},
+			},
+			2, errStoreDown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.CreateUsers(tt.users, AllOrNothing)
+			var batch *BatchError
+			if !errors.As(err, &batch) {
+				t.Fatalf("err = %v (%T), want *BatchError", err, err)
+			}
+			if len(batch.Records) != 1 || batch.Records[0].Index != tt.index || batch.Records[0].ID != tt.users[tt.index].ID {
+				t.Fatalf("records = %+v, want index %d", batch.Records, tt.index)
+			}
+			if !errors.Is(err, tt.cause) {
+				t.Fatalf("errors.Is(%v, %v) = false", err, tt.cause)
+			}
+			if result.Succeeded != 0 || s.Count() != 1 || s.Exists("u1") {
+				t.Fatalf("batch was not rolled back: %+v, count %d", result, s.Count())
+			}
+
+			// LoadJSON 原样返回同一个 *BatchError，不再包一层
+			data, _ := json.Marshal(tt.users)
+			err = s.LoadJSON(data, AllOrNothing)
+			if batch, ok := err.(*BatchError); !ok || len(batch.Records) != 1 || batch.Records[0].Index != tt.index {
+				t.Fatalf("LoadJSON = %v (%T)", err, err)
+			}
+		})
+	}
+}
+
+// WithUniqueEmails 的检查与写入在同一次写锁内：并发使用同一邮箱时只有一个成功
+func TestUniqueEmailsUnderConcurrentCreates(t *testing.T) {
+	const writers = 32
+	s := NewUserService(WithUniqueEmails(true))
+
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	start := make(chan struct{})
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = s.CreateUser(&User{ID: fmt.Sprintf("u%d", i), Name: "Same", Email: "same@example.com"})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		var dup *DuplicateEmailError
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.As(err, &dup) && errors.Is(err, ErrDuplicateEmail):
+			if dup.ID != fmt.Sprintf("u%d", i) {
+				t.Errorf("writer %d: %+v", i, dup)
+			}
+		default:
+			t.Errorf("writer %d: unexpected error %v", i, err)
+		}
+	}
+	if succeeded != 1 || s.Count() != 1 {
+		t.Fatalf("%d creates succeeded, Count() = %d, want exactly one", succeeded, s.Count())
+	}
+}
+
+// 更新与恢复同样不能占用别人的邮箱
+func TestUniqueEmailsRejectUpdateAndRestore(t *testing.T) {
+	s := NewUserService(WithUniqueEmails(true), WithSoftDelete())
+	mustCreate(t, s, "u1", "Ann", "ann@example.com")
+	mustCreate(t, s, "u2", "Bob", "bob@example.com")
+
+	var dup *DuplicateEmailError
+	taken := "ANN@example.com"
+	if _, err := s.UpdateUser("u2", UserPatch{Email: &taken}); !errors.As(err, &dup) || dup.OwnerID != "u1" {
+		t.Fatalf("UpdateUser onto a taken email = %v", err)
+	}
+	if user, _ := s.GetUser("u2"); user.Email != "bob@example.com" || user.Version != 1 {
+		t.Fatalf("rejected update changed the user: %+v", user)
+	}
+
+	// 删除期间邮箱被别人使用，恢复失败且墓碑保持原样
+	s.DeleteUser("u1")
+	mustCreate(t, s, "u3", "Ann", "ann@example.com")
+	if _, err := s.Restore("u1"); !errors.As(err, &dup) || dup.OwnerID != "u3" {
+		t.Fatalf("Restore onto a taken email = %v", err)
+	}
+	if _, err := s.GetUser("u1"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("rejected restore brought u1 back: %v", err)
+	}
+
+	// 邮箱释放后可以恢复
+	s.DeleteUser("u3")
+	if _, err := s.Restore("u1"); err != nil {
+		t.Fatalf("Restore after the email was freed: %v", err)
+	}
+}
+
+// recordErrors 把 RecordError 转为可比较的字符串，错误值本身是各自新建的指针
+func recordErrors(recs []RecordError) []string {
+	out := make([]string, len(recs))
+	for i, rec := range recs {
+		out[i] = rec.Error()
+	}
+	return out
+}
+
+// ValidateUsers 是 CreateUsers(batch, BestEffort) 的试运行：在相同的数据上报告完全相同的错误
+func TestValidateUsersMatchesBestEffortImport(t *testing.T) {
+	seed := func(t *testing.T) *UserService {
+		clock := newFakeClock()
+		s := NewUserService(WithUniqueEmails(true), WithSoftDelete(), WithClock(clock.Now), WithSweepInterval(0))
+		mustCreate(t, s, "u1", "Ann", "ann@example.com")
+		mustCreate(t, s, "u2", "Bob", "bob@example.com")
+		mustCreate(t, s, "gone", "Gus", "gone@example.com")
+		s.DeleteUser("gone")
+		if _, err := s.CreateUserWithTTL(&User{ID: "temp", Name: "Tia", Email: "temp@example.com"}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Minute)
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		batch []*User
+		want  []int // 出错记录的下标
+	}{
+		{
+			"duplicate email within the batch",
+			[]*User{
+				{ID: "n1", Name: "New", Email: "new@example.com"},
+				{ID: "n2", Name: "Newer", Email: "NEW@example.com"},
+			},
+			[]int{1},
+		},
+		{
+			"email held by an existing user",
+			[]*User{{ID: "n1", Name: "New", Email: "ann@example.com"}},
+			[]int{0},
+		},
+		{
+			"overwritten id frees its old email",
+			[]*User{
+				{ID: "u1", Name: "Ann", Email: "ann2@example.com"},
+				{ID: "n1", Name: "New", Email: "ann@example.com"},
+			},
+			nil,
+		},
+		{
+			"old email is still held before the overwrite",
+			[]*User{
+				{ID: "n1", Name: "New", Email: "ann@example.com"},
+				{ID: "u1", Name: "Ann", Email: "ann2@example.com"},
+			},
+			[]int{0},
+		},
+		{
+			"swap emails between two existing users",
+			[]*User{
+				{ID: "u1", Name: "Ann", Email: "bob@example.com"},
+				{ID: "u2", Name: "Bob", Email: "ann@example.com"},
+			},
+			// 第一条失败后 u1 仍持有原邮箱，第二条随之冲突
+			[]int{0, 1},
+		},
+		{
+			"email of a soft-deleted or expired owner is free",
+			[]*User{
+				{ID: "n1", Name: "New", Email: "gone@example.com"},
+				{ID: "n2", Name: "Newer", Email: "temp@example.com"},
+			},
+			nil,
+		},
+		{
+			"invalid records and duplicate ids",
+			[]*User{
+				{ID: "n1", Name: "New", Email: "not-an-email"},
+				{ID: "n2", Name: "Newer", Email: "n2@example.com"},
+				nil,
+				{ID: "n2", Name: "Again", Email: "again@example.com"},
+				{ID: "n3", Name: "Third", Email: "n2@example.com"},
+			},
+			[]int{0, 2, 3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dry := seed(t)
+			got := dry.ValidateUsers(tt.batch)
+
+			real := seed(t)
+			result, err := real.CreateUsers(tt.batch, BestEffort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(recordErrors(got), recordErrors(result.Errors)) {
+				t.Fatalf("ValidateUsers = %v\nCreateUsers  = %v", recordErrors(got), recordErrors(result.Errors))
+			}
+
+			indexes := make([]int, len(got))
+			for i, rec := range got {
+				indexes[i] = rec.Index
+			}
+			if !slices.Equal(indexes, tt.want) {
+				t.Fatalf("failed indexes = %v, want %v (%v)", indexes, tt.want, recordErrors(got))
+			}
+			// 试运行不写入任何数据
+			if n := dry.Count(); n != 2 {
+				t.Fatalf("ValidateUsers wrote to the store: Count() = %d", n)
+			}
+		})
+	}
+}
+
+// fakeMetrics 记录 UserService 上报的计数
+type fakeMetrics struct {
+	mu                                   sync.Mutex
+	gets, misses, creates, updates, dels int
+	lockWaits                            []time.Duration
+}
+
+func (m *fakeMetrics) IncrGet()    { m.add(&m.gets) }
+func (m *fakeMetrics) IncrMiss()   { m.add(&m.misses) }
+func (m *fakeMetrics) IncrCreate() { m.add(&m.creates) }
+func (m *fakeMetrics) IncrUpdate() { m.add(&m.updates) }
+func (m *fakeMetrics) IncrDelete() { m.add(&m.dels) }
+
+func (m *fakeMetrics) ObserveLockWait(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lockWaits = append(m.lockWaits, d)
+}
+
+func (m *fakeMetrics) add(counter *int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	*counter++
+}
+
+func TestMetricsCounters(t *testing.T) {
+	metrics := &fakeMetrics{}
+	s := NewUserService(WithMetrics(metrics))
+
+	mustCreate(t, s, "u1", "Alice", "alice@example.com")
+	mustCreate(t, s, "u2", "Bob", "bob@example.com")
+	mustCreate(t, s, "u1", "Alice", "alice@example.org") // 覆盖写入计为 Update
+	s.GetUser("u1")
+	s.GetUser("u2")
+	s.GetUser("missing")
+	name := "Bobby"
+	s.UpdateUser("u2", UserPatch{Name: &name})
+	s.DeleteUser("u2")
+	s.GetUser("u2")
+	s.DeleteUser("u2") // 不存在时不计数
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	// get、miss、create、update、delete
+	got := [5]int{metrics.gets, metrics.misses, metrics.creates, metrics.updates, metrics.dels}
+	if want := [5]int{4, 2, 2, 2, 1}; got != want {
+		t.Fatalf("counters = %v, want %v", got, want)
+	}
+	// 每次获取写锁上报一次等待时长：3 次创建、1 次更新、2 次删除
+	if len(metrics.lockWaits) != 6 {
+		t.Fatalf("lock waits = %d", len(metrics.lockWaits))
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  error // nil 表示接受
+	}{
+		{"alice@example.com", nil},
+		{"  alice@example.com\t", nil},
+		{"user+tag@example.com", nil},
+		{"first.last@sub.example.co", nil},
+		{"o'brien@example.io", nil},
+		{"a@xn--bcher-kva.example", nil},
+		{"", ErrEmailEmpty},
+		{"   ", ErrEmailEmpty},
+		{"alice example@example.com", ErrEmailWhitespace},
+		{"alice.example.com", ErrEmailMissingAt},
+		{"alice@localhost", ErrEmailMissingDomainDot},
+		{"alice@example..com", ErrEmailConsecutiveDots},
+		{"alice..smith@example.com", ErrEmailConsecutiveDots},
+		{".alice@example.com", ErrEmailMalformed},
+		{"alice@-example.com", ErrEmailMalformed},
+		{"alice@example.c", ErrEmailMalformed},
+		{"alice@example.123", ErrEmailMalformed},
+		{`"alice smith"@example.com`, ErrEmailWhitespace},
+		{"alice@[10.0.0.1]", ErrEmailMalformed},
+		{strings.Repeat("a", 65) + "@example.com", ErrEmailTooLong},
+		{"alice@" + strings.Repeat("a", 250) + ".com", ErrEmailTooLong},
+		{"alice@" + strings.Repeat("a", 64) + ".com", ErrEmailMalformed},
+	}
+	for _, tt := range tests {
+		err := ValidateEmailDetailed(tt.email)
+		if ValidateEmail(tt.email) != (tt.want == nil) {
+			t.Errorf("ValidateEmail(%q) = %v, want %v", tt.email, err == nil, tt.want == nil)
+		}
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("ValidateEmailDetailed(%q) = %v, want nil", tt.email, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.want) || !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("ValidateEmailDetailed(%q) = %v, want %v", tt.email, err, tt.want)
+		}
+	}
+}
+
+// fakeClock 是可手动推进的时钟，供 WithClock 使用
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestTTLExpiryWithFakeClock(t *testing.T) {
+	clock := newFakeClock()
+	s := NewUserService(WithClock(clock.Now), WithSweepInterval(0))
+	var events []ChangeEvent
+	s.OnChange(func(evt ChangeEvent) { events = append(events, evt) })
+
+	if _, err := s.CreateUserWithTTL(&User{ID: "u1", Name: "Alice", Email: "alice@example.com"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, s, "u2", "Bob", "bob@example.com")
+
+	clock.Advance(time.Minute - time.Second)
+	if _, err := s.GetUser("u1"); err != nil {
+		t.Fatalf("before expiry: %v", err)
+	}
+
+	// 到期时刻起视为不存在，但在 EvictExpired 之前仍留在 store 中
+	clock.Advance(time.Second)
+	if _, err := s.GetUser("u1"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("GetUser after expiry = %v", err)
+	}
+	if s.Exists("u1") || s.Count() != 1 {
+		t.Fatalf("Exists = %v, Count = %d", s.Exists("u1"), s.Count())
+	}
+	if _, err := s.FindByEmail("alice@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("FindByEmail after expiry = %v", err)
+	}
+	if list, _ := s.List(0, 0); !slices.Equal(ids(list), []string{"u2"}) {
+		t.Fatalf("List after expiry = %v", ids(list))
+	}
+
+	if n := s.EvictExpired(); n != 1 {
+		t.Fatalf("EvictExpired = %d", n)
+	}
+	if n := s.EvictExpired(); n != 0 {
+		t.Fatalf("second EvictExpired = %d", n)
+	}
+	last := events[len(events)-1]
+	if len(events) != 3 || last.Type != ChangeExpired || last.ID != "u1" {
+		t.Fatalf("events = %+v", events)
+	}
+}
+
+func TestTTLIsKeptByUpdateAndClearedByOverwrite(t *testing.T) {
+	clock := newFakeClock()
+	s := NewUserService(WithClock(clock.Now), WithSweepInterval(0))
+
+	if _, err := s.CreateUserWithTTL(&User{ID: "u1", Name: "Alice", Email: "alice@example.com"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateUserWithTTL(&User{ID: "u2", Name: "Bob", Email: "bob@example.com"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	name := "Alicia"
+	if _, err := s.UpdateUser("u1", UserPatch{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, s, "u2", "Bob", "bob@example.com")
+
+	clock.Advance(time.Minute)
+	if _, err := s.GetUser("u1"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("updated user should still expire: %v", err)
+	}
+	if user, err := s.GetUser("u2"); err != nil || user.ExpiresAt != nil {
+		t.Fatalf("overwritten user should not expire: %+v, %v", user, err)
+	}
+
+	if _, err := s.CreateUserWithTTL(&User{ID: "u3", Email: "c@example.com"}, 0); !errors.Is(err, ErrInvalidTTL) {
+		t.Fatalf("zero ttl = %v", err)
+	}
+}
+
+func TestJanitorEvictsExpiredUsersUntilClose(t *testing.T) {
+	clock := newFakeClock()
+	s := NewUserService(WithClock(clock.Now), WithSweepInterval(time.Millisecond))
+	expiredIDs := make(chan string, 2)
+	s.OnChange(func(evt ChangeEvent) {
+		if evt.Type == ChangeExpired {
+			expiredIDs <- evt.ID
+		}
+	})
+
+	if _, err := s.CreateUserWithTTL(&User{ID: "u1", Name: "Alice", Email: "alice@example.com"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	select {
+	case id := <-expiredIDs:
+		if id != "u1" {
+			t.Fatalf("expired %q", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("janitor did not evict the expired user")
+	}
+
+	// Close 等待 janitor 退出，可重复调用；之后过期的用户不再被自动清理
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateUserWithTTL(&User{ID: "u2", Name: "Bob", Email: "bob@example.com"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case id := <-expiredIDs:
+		t.Fatalf("janitor ran after Close and evicted %q", id)
+	default:
+	}
+	if n := s.EvictExpired(); n != 1 {
+		t.Fatalf("EvictExpired after Close = %d", n)
+	}
+}
+
+func TestRenameKeepsIndexesConsistent(t *testing.T) {
+	s := NewUserService(WithUniqueEmails(true))
+	mustCreate(t, s, "alice@example.com", "Alice", "alice@example.com")
+	mustCreate(t, s, "u2", "Bob", "bob@example.com")
+	var events []ChangeEvent
+	s.OnChange(func(evt ChangeEvent) { events = append(events, evt) })
+
+	if err := s.Rename("alice@example.com", "uuid-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != ChangeRenamed || events[0].ID != "uuid-1" ||
+		events[0].PreviousID != "alice@example.com" || events[0].User.ID != "uuid-1" {
+		t.Fatalf("events = %+v", events)
+	}
+
+	if _, err := s.GetUser("alice@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("old id still readable: %v", err)
+	}
+	if user, err := s.GetUser("uuid-1"); err != nil || user.Name != "Alice" {
+		t.Fatalf("GetUser(new) = %+v, %v", user, err)
+	}
+	if user, err := s.FindByEmail("alice@example.com"); err != nil || user.ID != "uuid-1" {
+		t.Fatalf("FindByEmail = %+v, %v", user, err)
+	}
+	if got := ids(s.SearchByNamePrefix("ali")); !slices.Equal(got, []string{"uuid-1"}) {
+		t.Fatalf("SearchByNamePrefix = %v", got)
+	}
+	if got := ids(s.SearchByNamePrefix("")); !slices.Equal(got, []string{"uuid-1", "u2"}) {
+		t.Fatalf("name index = %v", got)
+	}
+	if s.Count() != 2 {
+		t.Fatalf("Count = %d", s.Count())
+	}
+
+	// 邮箱索引指向新 ID：新 ID 可以继续使用该邮箱，其他用户仍被拒绝
+	email := "alice@example.com"
+	if _, err := s.UpdateUser("uuid-1", UserPatch{Email: &email}); err != nil {
+		t.Fatalf("renamed user cannot keep its email: %v", err)
+	}
+	var dup *DuplicateEmailError
+	if _, err := s.UpdateUser("u2", UserPatch{Email: &email}); !errors.As(err, &dup) || dup.OwnerID != "uuid-1" {
+		t.Fatalf("UpdateUser(u2) = %v", err)
+	}
+
+	if err := s.Rename("missing", "x"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("Rename(missing) = %v", err)
+	}
+	if err := s.Rename("uuid-1", "u2"); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("Rename onto existing = %v", err)
+	}
+	if user, _ := s.GetUser("u2"); user.Name != "Bob" {
+		t.Fatalf("failed rename changed the target: %+v", user)
+	}
+}
+
+// 覆盖墓碑或已过期用户时版本接着两者中较大的一个，newID 的版本不会回退
+func TestRenameOntoTombstoneContinuesVersion(t *testing.T) {
+	clock := newFakeClock()
+	s := NewUserService(WithSoftDelete(), WithClock(clock.Now), WithSweepInterval(0))
+	mustCreate(t, s, "old", "Alice", "alice@example.com")
+	mustCreate(t, s, "taken", "Bob", "bob@example.com")
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprint("Bob ", i)
+		if _, err := s.UpdateUser("taken", UserPatch{Name: &name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.DeleteUser("taken") // 墓碑版本为 7
+
+	if err := s.Rename("old", "taken"); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.GetUser("taken"); user.Version != 8 || user.Name != "Alice" {
+		t.Fatalf("renamed onto tombstone = %+v, want version 8", user)
+	}
+
+	// 已过期的目标同样参与比较；目标版本较低时沿用被移动用户的版本
+	if _, err := s.CreateUserWithTTL(&User{ID: "expired", Name: "Cid", Email: "cid@example.com"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	if err := s.Rename("taken", "expired"); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.GetUser("expired"); user.Version != 9 || user.Name != "Alice" {
+		t.Fatalf("renamed onto expired user = %+v, want version 9", user)
+	}
+
+	// 目标不存在时在原版本上加一
+	if err := s.Rename("expired", "fresh"); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.GetUser("fresh"); user.Version != 10 {
+		t.Fatalf("renamed onto free id = %+v, want version 10", user)
+	}
+}
+
+// 软删除的完整流程：删除留下墓碑，IncludeDeleted 可以读到，Restore 恢复并重建索引，Purge 只清理足够旧的墓碑
+func TestSoftDeleteRestoreAndPurge(t *testing.T) {
+	clock := newFakeClock()
+	s := NewUserService(WithSoftDelete(), WithUniqueEmails(true), WithClock(clock.Now), WithSweepInterval(0))
+	mustCreate(t, s, "u1", "Ann", "ann@example.com")
+	mustCreate(t, s, "u2", "Bob", "bob@example.com")
+	mustCreate(t, s, "u3", "Cid", "cid@example.com")
+
+	deletedAt := clock.Now()
+	if !s.DeleteUser("u1") {
+		t.Fatal("DeleteUser(u1) = false")
+	}
+	if _, err := s.GetUser("u1"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("GetUser after delete = %v", err)
+	}
+	if _, err := s.FindByEmail("ann@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("FindByEmail after delete = %v", err)
+	}
+	tombstone, err := s.GetUser("u1", IncludeDeleted())
+	if err != nil || tombstone.DeletedAt == nil || !tombstone.DeletedAt.Equal(deletedAt) || tombstone.Version != 2 {
+		t.Fatalf("GetUser(IncludeDeleted) = %+v, %v", tombstone, err)
+	}
+	if all, _ := s.List(0, 0, IncludeDeleted()); !slices.Equal(ids(all), []string{"u1", "u2", "u3"}) {
+		t.Fatalf("List(IncludeDeleted) = %v", ids(all))
+	}
+	if s.Count() != 2 {
+		t.Fatalf("Count() = %d, want 2", s.Count())
+	}
+
+	clock.Advance(30 * time.Minute)
+	restored, err := s.Restore("u1")
+	if err != nil || restored.DeletedAt != nil || restored.Version != 3 {
+		t.Fatalf("Restore = %+v, %v", restored, err)
+	}
+	if user, err := s.FindByEmail("ann@example.com"); err != nil || user.ID != "u1" {
+		t.Fatalf("email not re-indexed after Restore: %+v, %v", user, err)
+	}
+	if _, err := s.Restore("u1"); !errors.Is(err, ErrNotDeleted) {
+		t.Fatalf("Restore of a live user = %v", err)
+	}
+
+	s.DeleteUser("u2") // t0+30m
+	clock.Advance(time.Hour)
+	s.DeleteUser("u3") // t0+90m
+	clock.Advance(10 * time.Minute)
+
+	// cutoff 为 t0+70m：只有 u2 的墓碑足够旧
+	if n := s.Purge(30 * time.Minute); n != 1 {
+		t.Fatalf("Purge = %d, want 1", n)
+	}
+	if _, err := s.GetUser("u2", IncludeDeleted()); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("purged tombstone still readable: %v", err)
+	}
+	if _, err := s.GetUser("u3", IncludeDeleted()); err != nil {
+		t.Fatalf("recent tombstone was purged: %v", err)
+	}
+	if _, err := s.GetUser("u1"); err != nil {
+		t.Fatalf("live user was purged: %v", err)
+	}
+	if _, err := s.Restore("u2"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("Restore after Purge = %v", err)
+	}
+	if n := s.Purge(30 * time.Minute); n != 0 {
+		t.Fatalf("second Purge = %d, want 0", n)
+	}
+}
+
+// seedQueryStore 写入 u01..u10，名字在 "bob"、"Cid"、"Ann" 之间轮换；u03 额外更新一次使其版本为 2，u06 被软删除
+func seedQueryStore(t *testing.T) *UserService {
+	t.Helper()
+	s := NewUserService(WithSoftDelete())
+	names := []string{"Ann", "bob", "Cid"}
+	for i := 1; i <= 10; i++ {
+		id := fmt.Sprintf("u%02d", i)
+		mustCreate(t, s, id, names[i%3], fmt.Sprintf("%s@example.com", id))
+	}
+	name := "Ann"
+	if _, err := s.UpdateUser("u03", UserPatch{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	s.DeleteUser("u06")
+	return s
+}
+
+func TestQueryFilterOrderLimit(t *testing.T) {
+	s := seedQueryStore(t)
+	notAnn := func(u *User) bool { return u.Name != "Ann" }
+	odd := func(u *User) bool { return (u.ID[len(u.ID)-1]-'0')%2 == 1 }
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"all sorted by id", s.Query(), []string{"u01", "u02", "u03", "u04", "u05", "u07", "u08", "u09", "u10"}},
+		{"filter", s.Query().Where(notAnn), []string{"u01", "u02", "u04", "u05", "u07", "u08", "u10"}},
+		{"two filters", s.Query().Where(notAnn).Where(odd), []string{"u01", "u05", "u07"}},
+		// 名字忽略大小写排序，同名按 ID
+		{"order by name", s.Query().Where(notAnn).OrderBy(SortByName, true), []string{"u01", "u04", "u07", "u10", "u02", "u05", "u08"}},
+		{"order desc and limit", s.Query().Where(notAnn).OrderBy(SortByName, false).Limit(3), []string{"u02", "u05", "u08"}},
+		{"filter order offset limit", s.Query().Where(notAnn).OrderBy(SortByName, true).Offset(2).Limit(3), []string{"u07", "u10", "u02"}},
+		{"secondary order", s.Query().OrderBy(SortByVersion, false).OrderBy(SortByID, false).Limit(2), []string{"u03", "u10"}},
+		{"offset past end", s.Query().Where(odd).Offset(10), []string{}},
+		{"no match", s.Query().Where(func(*User) bool { return false }).Limit(5), []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := tt.query.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(users); !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderIsComposable(t *testing.T) {
+	s := seedQueryStore(t)
+	base := s.Query().Where(func(u *User) bool { return u.Name == "bob" })
+
+	byID := base.OrderBy(SortByID, false)
+	limited := base.Limit(1)
+	if got, _ := byID.Run(); !slices.Equal(ids(got), []string{"u10", "u07", "u04", "u01"}) {
+		t.Fatalf("derived order = %v", ids(got))
+	}
+	if got, _ := limited.Run(); !slices.Equal(ids(got), []string{"u01"}) {
+		t.Fatalf("derived limit = %v", ids(got))
+	}
+	// 派生查询不影响基础查询
+	if got, _ := base.Run(); !slices.Equal(ids(got), []string{"u01", "u04", "u07", "u10"}) {
+		t.Fatalf("base = %v", ids(got))
+	}
+
+	// 结果是副本
+	got, _ := base.Run()
+	got[0].Name = "changed"
+	if user, _ := s.GetUser("u01"); user.Name != "bob" {
+		t.Fatalf("query result aliases the store: %+v", user)
+	}
+}
+
+// 过滤条件在锁外执行：条件内读取 UserService、同时有写操作排队也不会死锁
+func TestQueryWhereMayCallServiceWhileWriterWaits(t *testing.T) {
+	s := seedQueryStore(t)
+	var once sync.Once
+	pred := func(u *User) bool {
+		once.Do(func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				if _, err := s.CreateUser(&User{ID: "u11", Name: "Dee", Email: "u11@example.com"}); err != nil {
+					t.Error(err)
+				}
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("writer blocked by a running query")
+			}
+		})
+		owner, err := s.FindByEmail(u.Email)
+		return err == nil && owner.ID == u.ID
+	}
+
+	users, err := s.Query().Where(pred).Limit(3).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 候选用户在写入之前复制，u11 不在本次结果中
+	if got := ids(users); !slices.Equal(got, []string{"u01", "u02", "u03"}) {
+		t.Fatalf("got %v", got)
+	}
+	if !s.Exists("u11") {
+		t.Fatal("write during query was lost")
+	}
+}
+
+// fn 在锁外调用：遍历期间可以写入，写入不影响本次遍历
+func TestForEachMayWriteDuringIteration(t *testing.T) {
+	for _, shards := range []int{1, 8} {
+		t.Run(fmt.Sprintf("shards=%d", shards), func(t *testing.T) {
+			s := NewUserServiceSharded(shards)
+			for i := 0; i < 20; i++ {
+				mustCreate(t, s, fmt.Sprintf("u%02d", i), "user", fmt.Sprintf("u%02d@example.com", i))
+			}
+
+			visited := 0
+			s.ForEach(func(u *User) bool {
+				visited++
+				name := strings.ToUpper(u.Name)
+				if _, err := s.UpdateUser(u.ID, UserPatch{Name: &name}); err != nil {
+					t.Error(err)
+				}
+				mustCreate(t, s, "new-"+u.ID, "new", "new-"+u.Email)
+				return true
+			})
+			if visited != 20 {
+				t.Fatalf("visited %d users, want 20", visited)
+			}
+			if n := s.Count(); n != 40 {
+				t.Fatalf("Count() = %d, want 40", n)
+			}
+			if user, _ := s.GetUser("u07"); user.Name != "USER" {
+				t.Fatalf("update during ForEach lost: %+v", user)
+			}
+
+			stopped := 0
+			s.ForEach(func(*User) bool {
+				stopped++
+				return stopped < 3
+			})
+			if stopped != 3 {
+				t.Fatalf("ForEach did not stop early: %d calls", stopped)
+			}
+		})
+	}
+}
+
+// 固定的分片结果：FNV-1a 32 位哈希对 shards 取模，任何变化都会改变已部署集群中用户的归属
+func TestShardForIsPinned(t *testing.T) {
+	tests := []struct {
+		id     string
+		shards int
+		want   int
+	}{
+		{"", 7, 2},
+		{"", 32, 5},
+		{"a", 7, 5},
+		{"a", 32, 12},
+		{"u1", 7, 4},
+		{"u1", 32, 19},
+		{"alice@example.com", 7, 3},
+		{"alice@example.com", 32, 6},
+		{"a", 1, 0},
+		{"a", 0, 0},
+		{"a", -3, 0},
+	}
+	for _, tt := range tests {
+		if got := ShardFor(tt.id, tt.shards); got != tt.want {
+			t.Errorf("ShardFor(%q, %d) = %d, want %d", tt.id, tt.shards, got, tt.want)
+		}
+	}
+}
+
+func TestShardForMatchesMemoryStore(t *testing.T) {
+	store := NewShardedMemoryStore(7)
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("user-%d", i)
+		if _, err := store.Put(context.Background(), &User{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := store.shards[ShardFor(id, 7)].users[id]; !ok {
+			t.Fatalf("%s is not stored in shard %d", id, ShardFor(id, 7))
+		}
+	}
+}
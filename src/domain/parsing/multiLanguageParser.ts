@@ -44,19 +44,131 @@ interface ImportExportItem {
   [key: string]: unknown;
 }
 
-interface ParseError {
+export interface ImportDeclaration {
+  source: string;
+  specifiers: string[];
+  file_path: string;
+  is_type_only: boolean;
+  /** 导入别名（Go 的 `alias "path"`、`.`、`_`） */
+  alias?: string;
+}
+
+export type SymbolKind = 'function' | 'method' | 'type' | 'interface' | 'constant' | 'variable';
+
+/**
+ * 符号位置：行号 1-based，字节偏移为 UTF-8 源码的半开区间
+ * （JS 字符串按 UTF-16 索引，截取原文需先转换为 Buffer）
+ */
+export interface SymbolSpan {
+  startLine: number;
+  endLine: number;
+  startByte: number;
+  endByte: number;
+}
+
+/** 语法诊断：解析遇到语法错误时仍返回其余符号，错误位置记录在这里 */
+export interface Diagnostic {
   message: string;
-  line?: number;
-  [key: string]: unknown;
+  span: SymbolSpan;
+}
+
+export interface SymbolReceiver {
+  name?: string;
+  typeName: string;
+  isPointer: boolean;
+}
+
+export interface SymbolField {
+  name: string;
+  fieldType: string;
+  tag?: string;
+  isEmbedded: boolean;
+}
+
+/**
+ * 函数参数；可变参数的 paramType 为元素类型，无类型注解时为空字符串
+ */
+export interface SymbolParam {
+  name?: string;
+  paramType: string;
+  isVariadic: boolean;
+}
+
+/** 泛型类型参数（目前仅 Go），如 `[K comparable, V any]` 中的 K 与 V */
+export interface TypeParam {
+  name: string;
+  constraint: string;
+}
+
+/** 接口方法集中的一个方法，签名格式与函数相同 */
+export interface InterfaceMethod {
+  name: string;
+  signature: string;
+  params?: SymbolParam[];
+  returns?: string[];
+}
+
+/**
+ * 函数体内的调用点（语法层面）
+ */
+export interface CallRef {
+  name: string;
+  /** 接收者或包表达式，如 `regexp`、`s.mu` */
+  receiver?: string;
+}
+
+/**
+ * 结构化符号（仅已实现符号提取的语言会填充）
+ */
+export interface ParsedSymbol {
+  name: string;
+  kind: SymbolKind;
+  span: SymbolSpan;
+  receiver?: SymbolReceiver;
+  fields?: SymbolField[];
+  /** 接口声明的方法（不含嵌入接口的方法，嵌入接口见 fields） */
+  methods?: InterfaceMethod[];
+  docstring?: string;
+  /** 规范化签名（Go 只含参数/返回值类型；Python/JavaScript 保留参数名），不含空白 */
+  signature: string;
+  params?: SymbolParam[];
+  /** 返回值类型（Go 的具名返回值只保留类型） */
+  returns?: string[];
+  typeParams?: TypeParam[];
+  /** 稳定 ID：与位置无关，用于索引时的幂等 upsert */
+  stableId: string;
+  calls?: CallRef[];
+  isExported: boolean;
+  /** 符号源码的 FNV-1a 哈希（十六进制） */
+  contentHash: string;
+}
+
+/**
+ * 两次解析之间的符号差异（方法按 `Receiver.Name` 匹配）
+ */
+export interface SymbolDiff {
+  added: ParsedSymbol[];
+  removed: ParsedSymbol[];
+  modified: ParsedSymbol[];
+  moved: ParsedSymbol[];
+  packageChanged: boolean;
+  importsChanged: boolean;
+}
+
+export interface IncrementalParseResult {
+  result: ParseResult;
+  diff: SymbolDiff;
 }
 
 export interface ParseResult {
   filePath: string;
   language: string;
   entities: string[];
-  imports: ImportExportItem[];
+  symbols: ParsedSymbol[];
+  package?: string;
+  imports: ImportDeclaration[];
   exports: ImportExportItem[];
-  errors: ParseError[];
+  diagnostics: Diagnostic[];
 }
 
 export interface ParseStats {
@@ -85,6 +197,9 @@ interface NativeLanguageManager {
   parseFile(filePath: string, content: string): string;
   parseFilesBatch(files: Array<[string, string]>): string[];
   guessLanguage(filePath: string): string | null;
+  detectLanguage(filePath: string, content: string): string | null;
+  reparseFile(previousJson: string, content: string): string;
+  forgetFile(filePath: string): void;
 }
 
 export class MultiLanguageParser {
@@ -121,6 +236,30 @@ export class MultiLanguageParser {
     }
   }
 
+  /**
+   * 增量重新解析（watch 模式）
+   *
+   * 复用上次该文件的语法树，只把变化的符号放在 diff 中；不再监听的文件调用 forgetFile 释放缓存
+   *
+   * @param previous - 上次的解析结果
+   * @param content - 新的文件内容
+   */
+  async reparseFile(previous: ParseResult, content: string): Promise<IncrementalParseResult> {
+    try {
+      const jsonResult = this.manager.reparseFile(JSON.stringify(previous), content);
+      return JSON.parse(jsonResult) as IncrementalParseResult;
+    } catch (error) {
+      throw new Error(`Failed to reparse ${previous.filePath}: ${error}`);
+    }
+  }
+
+  /**
+   * 释放文件的增量解析缓存
+   */
+  forgetFile(filePath: string): void {
+    this.manager.forgetFile(filePath);
+  }
+
   /**
    * 批量解析文件（性能优化版本）
    *
@@ -143,11 +282,17 @@ export class MultiLanguageParser {
   /**
    * 检测文件语言
    *
+   * 提供 content 时，扩展名缺失或无法识别会退回到内容启发式（shebang、package 声明）
+   *
    * @param filePath - 文件路径
-   * @returns 语言名称或 null（不支持的文件类型）
+   * @param content - 文件内容（可选）
+   * @returns 语言名称或 null（无法识别，调用方可跳过该文件）
    */
-  detectLanguage(filePath: string): SupportedLanguage | null {
+  detectLanguage(filePath: string, content?: string): SupportedLanguage | null {
     try {
+      if (content !== undefined) {
+        return this.manager.detectLanguage(filePath, content) as SupportedLanguage | null;
+      }
       return this.manager.guessLanguage(filePath) as SupportedLanguage | null;
     } catch {
       return null;
@@ -190,7 +335,7 @@ export class MultiLanguageParser {
     iterations: number = 100,
   ): Promise<number> {
     const parser = new MultiLanguageParser();
-    const filePath = `test.${getExtension(language)}`;
+    const filePath = `test.${getLanguageExtension(language)}`;
 
     const start = Date.now();
     for (let i = 0; i < iterations; i++) {
@@ -223,7 +368,7 @@ export function createMultiLanguageParser(): MultiLanguageParser | null {
 /**
  * 获取语言对应的文件扩展名
  */
-function getExtension(language: SupportedLanguage): string {
+export function getLanguageExtension(language: SupportedLanguage): string {
   const map: Record<SupportedLanguage, string> = {
     TypeScript: 'ts',
     JavaScript: 'js',
@@ -2,6 +2,7 @@ import fs from 'node:fs/promises';
 import path from 'node:path';
 
 import type { McpServer } from '@modelcontextprotocol/sdk/server/mcp.js';
+import { ErrorCode, McpError } from '@modelcontextprotocol/sdk/types.js';
 import { z } from 'zod';
 
 import { ProjectService } from '../services/projectService.js';
@@ -35,6 +36,13 @@ import {
   type GenerateDocsResult,
 } from '../services/documentationGenerator.js';
 import type { QueryService } from '../domain/query/queryService.js';
+import {
+  createMultiLanguageParser,
+  type Diagnostic,
+  getLanguageExtension,
+  type MultiLanguageParser,
+  type ParsedSymbol,
+} from '../domain/parsing/multiLanguageParser.js';
 
 export interface CodeToolDependencies {
   readFile: typeof fs.readFile;
@@ -47,6 +55,7 @@ export interface CodeToolDependencies {
   codeSmellDetector?: CodeSmellDetector;
   refactoringSuggester?: RefactoringSuggester;
   documentationGenerator?: DocumentationGenerator;
+  multiLanguageParser?: MultiLanguageParser;
 }
 
 const defaultDeps: CodeToolDependencies = {
@@ -55,6 +64,80 @@ const defaultDeps: CodeToolDependencies = {
   mkdir: fs.mkdir,
 };
 
+const symbolSpanSchema = z.object({
+  startLine: z.number().int().describe('1-based start line (inclusive)'),
+  endLine: z.number().int().describe('1-based end line (inclusive)'),
+  startByte: z.number().int().describe('UTF-8 byte offset of the first byte'),
+  endByte: z.number().int().describe('UTF-8 byte offset one past the last byte'),
+});
+
+const symbolParamSchema = z.object({
+  name: z.string().optional(),
+  paramType: z.string(),
+  isVariadic: z.boolean(),
+});
+
+const parsedSymbolSchema = z.object({
+  name: z.string(),
+  kind: z.enum(['function', 'method', 'type', 'interface', 'constant', 'variable']),
+  span: symbolSpanSchema,
+  receiver: z
+    .object({
+      name: z.string().optional(),
+      typeName: z.string(),
+      isPointer: z.boolean(),
+    })
+    .optional()
+    .describe('Method receiver (Go)'),
+  fields: z
+    .array(
+      z.object({
+        name: z.string(),
+        fieldType: z.string(),
+        tag: z.string().optional(),
+        isEmbedded: z.boolean(),
+      }),
+    )
+    .optional()
+    .describe('Struct fields; embedded interfaces of an interface appear as embedded fields'),
+  methods: z
+    .array(
+      z.object({
+        name: z.string(),
+        signature: z.string(),
+        params: z.array(symbolParamSchema).optional(),
+        returns: z.array(z.string()).optional(),
+      }),
+    )
+    .optional()
+    .describe('Method set declared by an interface'),
+  docstring: z.string().optional().describe('Leading doc comment without delimiters'),
+  signature: z.string().describe('Normalized signature (parameter/result types only)'),
+  params: z
+    .array(symbolParamSchema)
+    .optional()
+    .describe('Function parameters in declaration order (variadic params carry the element type)'),
+  returns: z.array(z.string()).optional().describe('Return types (named results keep only the type)'),
+  typeParams: z
+    .array(z.object({ name: z.string(), constraint: z.string() }))
+    .optional()
+    .describe('Generic type parameters with their constraints (Go)'),
+  stableId: z
+    .string()
+    .describe('Position-independent ID (language, package dir or file, receiver, name, signature)'),
+  calls: z
+    .array(z.object({ name: z.string(), receiver: z.string().optional() }))
+    .optional()
+    .describe('Syntactic call sites in the body (callee name + receiver/package expression)'),
+  isExported: z.boolean(),
+  contentHash: z.string().describe('FNV-1a hash of the symbol source (hex)'),
+});
+
+const diagnosticSchema = z.object({
+  message: z.string(),
+  span: symbolSpanSchema,
+});
+
 function resolveSafePath(projectPath: string, file: string): string {
   const root = path.resolve(projectPath);
   const full = path.resolve(root, file);
@@ -92,6 +175,15 @@ export function registerCodeTools(
       ? new DocumentationGenerator({ queryService: deps.queryService })
       : undefined);
 
+  // 原生解析器按需加载，未构建时只在调用 code.parseFile 时报错
+  let multiLanguageParser: MultiLanguageParser | null | undefined = deps.multiLanguageParser;
+  const getMultiLanguageParser = (): MultiLanguageParser | null => {
+    if (multiLanguageParser === undefined) {
+      multiLanguageParser = createMultiLanguageParser();
+    }
+    return multiLanguageParser;
+  };
+
   server.registerTool(
     'code.readFile',
     {
@@ -572,6 +664,130 @@ export function registerCodeTools(
       };
     },
   );
+
+  server.registerTool(
+    'code.parseFile',
+    {
+      title: 'Parse a file into structured symbols',
+      description:
+        'Parse a project file or raw source content and return structured symbols ' +
+        '(functions, methods, types) with spans, receivers, fields and doc comments. ' +
+        'Provide either projectPath + file, or content + language.',
+      inputSchema: {
+        projectPath: z.string().optional().describe('Project root path (required with file)'),
+        file: z.string().optional().describe('File path relative to projectPath'),
+        content: z.string().optional().describe('Raw source code (overrides reading file)'),
+        language: z
+          .string()
+          .optional()
+          .describe(
+            'Language hint, case-insensitive (e.g. "Go" or "go"); ' +
+              'detected from the file extension or content when omitted',
+          ),
+      },
+      outputSchema: {
+        filePath: z.string(),
+        language: z.string(),
+        symbols: z.array(parsedSymbolSchema),
+        diagnostics: z
+          .array(diagnosticSchema)
+          .describe('Syntax errors; symbols outside the broken regions are still returned'),
+      },
+    },
+    async ({ projectPath, file, content, language }) => {
+      if (content === undefined && (!projectPath || !file)) {
+        throw new McpError(
+          ErrorCode.InvalidParams,
+          'code.parseFile requires either content or projectPath + file',
+        );
+      }
+
+      const parser = getMultiLanguageParser();
+      if (!parser) {
+        throw new McpError(
+          ErrorCode.InternalError,
+          'code.parseFile requires the native multi-language parser (run "pnpm build:rust")',
+        );
+      }
+
+      const source =
+        content ?? (await resolvedDeps.readFile(resolveSafePath(projectPath!, file!), 'utf8'));
+
+      // 扩展名优先，没有文件名或扩展名无法识别时按内容（shebang、package 声明）判断
+      const supported = parser.getSupportedLanguages();
+      const detected = parser.detectLanguage(file ?? '', source);
+      // 提示不区分大小写，统一成 getSupportedLanguages 中的写法
+      const hinted =
+        language === undefined
+          ? undefined
+          : supported.find((name) => name.toLowerCase() === language.toLowerCase());
+      const resolvedLanguage = language === undefined ? detected : hinted;
+      if (!resolvedLanguage || !supported.includes(resolvedLanguage)) {
+        throw new McpError(
+          ErrorCode.InvalidParams,
+          `Unsupported language: ${language ?? file ?? 'unknown'}`,
+          { code: 'UNSUPPORTED_LANGUAGE', supportedLanguages: supported },
+        );
+      }
+
+      // 提示与检测结果不一致时改用合成文件名，让原生解析器按提示的语言解析
+      const parsePath =
+        file && detected === resolvedLanguage
+          ? file
+          : `snippet.${getLanguageExtension(resolvedLanguage)}`;
+      const parsed = await parser.parseFile(parsePath, source);
+
+      const result = {
+        filePath: file ?? parsePath,
+        language: resolvedLanguage,
+        symbols: parsed.symbols ?? [],
+        diagnostics: parsed.diagnostics ?? [],
+      };
+
+      return {
+        content: [{ type: 'text', text: formatParseFileResult(result) }],
+        structuredContent: result as unknown as { [x: string]: unknown },
+      };
+    },
+  );
+}
+
+/**
+ * Format parsed symbols for human-readable output
+ */
+function formatParseFileResult(result: {
+  filePath: string;
+  language: string;
+  symbols: ParsedSymbol[];
+  diagnostics: Diagnostic[];
+}): string {
+  const lines: string[] = [];
+
+  lines.push(`# Symbols: \`${result.filePath}\``);
+  lines.push('');
+  lines.push(`**Language**: ${result.language}`);
+  lines.push(`**Symbols**: ${result.symbols.length}`);
+  lines.push('');
+
+  for (const symbol of result.symbols) {
+    const owner = symbol.receiver
+      ? `(${symbol.receiver.isPointer ? '*' : ''}${symbol.receiver.typeName}).`
+      : '';
+    lines.push(
+      `- \`${owner}${symbol.name}\` (${symbol.kind}) ` +
+        `L${symbol.span.startLine}-${symbol.span.endLine}`,
+    );
+  }
+
+  if (result.diagnostics.length > 0) {
+    lines.push('');
+    lines.push(`**Diagnostics**: ${result.diagnostics.length}`);
+    for (const diagnostic of result.diagnostics) {
+      lines.push(`- L${diagnostic.span.startLine}: ${diagnostic.message}`);
+    }
+  }
+
+  return lines.join('\n');
 }
 
 /**
@@ -64,6 +64,13 @@ describe('MultiLanguageParser E2E', () => {
         expect(parser.detectLanguage('test.unknown')).toBeNull();
       });
 
+      it('should fall back to content heuristics', () => {
+        const goCode = readFileSync(join(FIXTURES_DIR, 'sample.go'), 'utf-8');
+        expect(parser.detectLanguage('sample', goCode)).toBe('Go');
+        expect(parser.detectLanguage('tool', '#!/usr/bin/env node\n')).toBe('JavaScript');
+        expect(parser.detectLanguage('README', 'hello world')).toBeNull();
+      });
+
       it('should get supported languages list', () => {
         const languages = parser.getSupportedLanguages();
         console.log('Supported languages:', languages);
@@ -144,6 +151,17 @@ describe('MultiLanguageParser E2E', () => {
         const hasFunction = result.entities.some((e) => e.includes('def validate_email'));
         expect(hasFunction).toBe(true);
       });
+
+      it('should map classes to type symbols with methods', async () => {
+        const result = await parser.parseFile('sample.py', sampleCode);
+        const service = result.symbols.find((s) => s.name === 'UserService');
+        const getUser = result.symbols.find((s) => s.name === 'get_user');
+
+        expect(service?.kind).toBe('type');
+        expect(getUser?.kind).toBe('method');
+        expect(getUser?.receiver?.typeName).toBe('UserService');
+        expect(getUser?.docstring).toBe('获取用户信息');
+      });
     });
 
     describe('Go parsing', () => {
@@ -181,6 +199,51 @@ describe('MultiLanguageParser E2E', () => {
         );
         expect(hasFunction).toBe(true);
       });
+
+      it('should attach receivers to methods', async () => {
+        const result = await parser.parseFile('sample.go', sampleCode);
+        const getUser = result.symbols.find((s) => s.name === 'GetUser');
+
+        expect(getUser?.kind).toBe('method');
+        expect(getUser?.receiver).toMatchObject({ typeName: 'UserService', isPointer: true });
+      });
+
+      it('should report only changed symbols on reparse', async () => {
+        const previous = await parser.parseFile('sample.go', sampleCode);
+        const changed = sampleCode.replace(
+          'errors.New("user not found")',
+          'errors.New("no such user")',
+        );
+
+        const { diff } = await parser.reparseFile(previous, changed);
+        parser.forgetFile('sample.go');
+
+        expect(diff.modified.map((s) => s.name)).toEqual(['GetUser']);
+        expect(diff.added).toEqual([]);
+        expect(diff.removed).toEqual([]);
+      });
+
+      it('should expose byte-accurate spans', async () => {
+        const result = await parser.parseFile('sample.go', sampleCode);
+        const getUser = result.symbols.find((s) => s.name === 'GetUser')!;
+        const body = Buffer.from(sampleCode, 'utf-8')
+          .subarray(getUser.span.startByte, getUser.span.endByte)
+          .toString('utf-8');
+
+        expect(body.startsWith('func (s *UserService) GetUser(')).toBe(true);
+        expect(body.endsWith('}')).toBe(true);
+        expect(getUser.span.endLine).toBeGreaterThan(getUser.span.startLine);
+      });
+
+      it('should return partial symbols and diagnostics for broken files', async () => {
+        const broken = readFileSync(join(FIXTURES_DIR, '../broken/broken.go'), 'utf-8');
+        const result = await parser.parseFile('broken.go', broken);
+        const names = result.symbols.map((s) => s.name);
+
+        expect(names).toEqual(expect.arrayContaining(['Greeter', 'Greet', 'Shout', 'Loud']));
+        expect(result.diagnostics.length).toBeGreaterThan(0);
+        expect((await parser.parseFile('sample.go', sampleCode)).diagnostics).toEqual([]);
+      });
     });
 
     describe('Rust parsing', () => {
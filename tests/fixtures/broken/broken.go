@@ -0,0 +1,32 @@
+// broken.go 故意包含语法错误，用于测试解析器的容错：错误前后的声明都应被提取
+package broken
+
+import "strings"
+
+// Greeter 声明在语法错误之前
+type Greeter struct {
+	Prefix string
+}
+
+// Greet 返回带前缀的问候语
+func (g *Greeter) Greet(name string) string {
+	return g.Prefix + strings.TrimSpace(name)
+}
+
+// Broken 的返回表达式缺少右操作数
+func Broken(a int) int {
+	return a +
+}
+
+// 顶层不允许出现短变量声明
+counter := 0
+
+// Shout 声明在语法错误之后
+func Shout(s string) string {
+	return strings.ToUpper(s) + "!"
+}
+
+// Loud 是 Shout 之后的另一个有效类型
+type Loud interface {
+	Shout(s string) string
+}
@@ -0,0 +1,51 @@
+// Go 顶层声明测试文件：与 sample.go 同属 main 包，覆盖常量、包级变量与接口
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Role 是用户在系统中的角色
+type Role int
+
+// 角色按权限从低到高排列
+const (
+	RoleGuest Role = iota
+	RoleMember
+	// RoleAdmin 可以管理其他用户
+	RoleAdmin
+)
+
+// MaxNameLength 限制用户名的字符数
+const MaxNameLength = 64
+
+// defaultRole 是新用户的默认角色
+var defaultRole = RoleGuest
+
+var (
+	// ErrReadOnlyStore 由只读 Store 的写方法返回
+	ErrReadOnlyStore = errors.New("store is read-only")
+	roleNames        = map[Role]string{RoleGuest: "guest", RoleMember: "member", RoleAdmin: "admin"}
+)
+
+// String 返回角色名，未知角色返回 "unknown"
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// UserReader 是只读的用户来源
+type UserReader interface {
+	Get(ctx context.Context, id string) (*User, error)
+	List(ctx context.Context) ([]*User, error)
+}
+
+// UserExporter 在 UserReader 之上增加导出能力
+type UserExporter interface {
+	UserReader
+	Export(ctx context.Context, w io.Writer) (n int64, err error)
+}
@@ -0,0 +1,69 @@
+// Go 泛型测试文件：与 sample.go 同属 main 包，覆盖类型参数、约束与泛型接收者
+package main
+
+import "fmt"
+
+// Number 是数值类型的约束，使用近似元素与联合
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+// Map 对每个元素应用 fn，T 与 U 共享同一个约束
+func Map[T, U any](items []T, fn func(T) U) []U {
+	out := make([]U, 0, len(items))
+	for _, item := range items {
+		out = append(out, fn(item))
+	}
+	return out
+}
+
+// Sum 累加所有元素
+func Sum[N Number](values ...N) N {
+	var total N
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Keys 返回 map 的全部键，顺序不确定
+func Keys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Pair 是有两个类型参数的泛型结构体
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Stack 是后进先出的泛型栈
+type Stack[T any] struct {
+	items []T
+}
+
+// Push 压入一个元素
+func (s *Stack[T]) Push(item T) {
+	s.items = append(s.items, item)
+}
+
+// Pop 弹出栈顶元素，栈为空时 ok 为 false
+func (s *Stack[T]) Pop() (item T, ok bool) {
+	if len(s.items) == 0 {
+		return item, false
+	}
+	item = s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return item, true
+}
+
+// Labels 把用户列表映射为展示用的字符串，显式实例化 Map
+func Labels(users []*User) []string {
+	return Map[*User, string](users, func(u *User) string {
+		return fmt.Sprintf("%s <%s>", u.Name, u.Email)
+	})
+}
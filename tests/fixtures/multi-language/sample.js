@@ -0,0 +1,73 @@
+// JavaScript 测试文件
+
+export const EMAIL_PATTERN = /^[^\s@]+@[^\s@]+\.[^\s@]+$/;
+
+export class UserNotFoundError extends Error {
+  constructor(id) {
+    super(`user not found: ${id}`);
+    this.id = id;
+  }
+}
+
+/**
+ * UserService 管理用户，按 ID 存储并维护邮箱索引
+ * （与 sample.go 中的 UserService 对应）
+ */
+export class UserService {
+  users = new Map();
+  byEmail = new Map();
+  #validator;
+
+  constructor(validator = validateEmail) {
+    this.#validator = validator;
+  }
+
+  /** 获取用户信息 */
+  async getUser(id) {
+    return this.users.get(id) ?? null;
+  }
+
+  /** 创建新用户 */
+  async createUser(user) {
+    if (!this.#validator(user.email)) {
+      throw new TypeError(`invalid email: ${user.email}`);
+    }
+    this.users.set(user.id, user);
+    this.#indexEmail(user);
+  }
+
+  /** 删除用户 */
+  async deleteUser(id) {
+    return this.users.delete(id);
+  }
+
+  findByEmail(email) {
+    const id = this.byEmail.get(email.toLowerCase());
+    if (id === undefined) {
+      throw new UserNotFoundError(email);
+    }
+    return this.users.get(id);
+  }
+
+  // 按 ID 升序分页返回用户
+  listUsers(offset = 0, limit = 0) {
+    const users = [...this.users.values()].sort((a, b) => a.id.localeCompare(b.id));
+    return limit > 0 ? users.slice(offset, offset + limit) : users.slice(offset);
+  }
+
+  #indexEmail(user) {
+    this.byEmail.set(user.email.toLowerCase(), user.id);
+  }
+}
+
+/** 验证邮箱格式 */
+export function validateEmail(email) {
+  return EMAIL_PATTERN.test(email);
+}
+
+const normalizeEmail = (email) => {
+  const [local, domain = ''] = email.trim().split('@');
+  return `${local}@${domain.toLowerCase()}`;
+};
+
+export default UserService;
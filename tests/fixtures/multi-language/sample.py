@@ -1,25 +1,44 @@
 # Python 测试文件
-from typing import Optional, Dict
+import re
+from typing import Callable, Dict, List, Optional
 from dataclasses import dataclass
 
+EMAIL_PATTERN = r'^[^\s@]+@[^\s@]+\.[^\s@]+$'
+
+
+class UserNotFound(KeyError):
+    """用户不存在"""
+
+
 @dataclass
 class User:
     id: str
     name: str
     email: str
 
+
+# UserService 管理用户，按 ID 存储并维护邮箱索引
 class UserService:
-    def __init__(self):
+    """用户服务（与 sample.go 中的 UserService 对应）"""
+
+    max_batch: int = 100
+
+    def __init__(self, validator: Optional[Callable[[str], bool]] = None):
         self.users: Dict[str, User] = {}
-    
+        self.by_email: Dict[str, str] = {}
+        self.validator = validator or validate_email
+
     async def get_user(self, user_id: str) -> Optional[User]:
         """获取用户信息"""
         return self.users.get(user_id)
-    
+
     async def create_user(self, user: User) -> None:
         """创建新用户"""
+        if not self.validator(user.email):
+            raise ValueError(f"invalid email: {user.email}")
         self.users[user.id] = user
-    
+        self._index_email(user)
+
     async def delete_user(self, user_id: str) -> bool:
         """删除用户"""
         if user_id in self.users:
@@ -27,8 +46,30 @@ class UserService:
             return True
         return False
 
+    def find_by_email(self, email: str) -> User:
+        user_id = self.by_email.get(email.lower())
+        if user_id is None:
+            raise UserNotFound(email)
+        return self.users[user_id]
+
+    def list_users(self, offset: int = 0, limit: int = 0) -> List[User]:
+        """按 ID 升序分页返回用户"""
+        users = sorted(self.users.values(), key=lambda u: u.id)
+        return users[offset:offset + limit] if limit > 0 else users[offset:]
+
+    @staticmethod
+    def shard_for(user_id: str, shards: int) -> int:
+        return hash(user_id) % shards
+
+    def _index_email(self, user: User) -> None:
+        self.by_email[user.email.lower()] = user.id
+
+
 def validate_email(email: str) -> bool:
     """验证邮箱格式"""
-    import re
-    pattern = r'^[^\s@]+@[^\s@]+\.[^\s@]+$'
-    return bool(re.match(pattern, email))
+    return bool(re.match(EMAIL_PATTERN, email))
+
+
+def _normalize_email(email: str) -> str:
+    local, _, domain = email.strip().partition('@')
+    return f"{local}@{domain.lower()}"
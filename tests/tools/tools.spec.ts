@@ -279,6 +279,119 @@ describe('MCP tool registration', () => {
     expect(writeResult?.structuredContent.ok).toBe(true);
   });
 
+  it('code.parseFile returns symbols for raw content and rejects unsupported languages', async () => {
+    const server = new StubServer();
+    const symbol = {
+      name: 'GetUser',
+      kind: 'method',
+      span: { startLine: 3, endLine: 5, startByte: 14, endByte: 80 },
+      receiver: { name: 's', typeName: 'UserService', isPointer: true },
+      docstring: 'GetUser 获取用户',
+      signature: '(string)(*User,error)',
+      stableId: 'cddde5c7bb37442d',
+      isExported: true,
+      contentHash: '85944171f73967e8',
+    };
+    const parser = {
+      getSupportedLanguages: vi.fn().mockReturnValue(['TypeScript', 'Go']),
+      detectLanguage: vi.fn().mockReturnValue(null),
+      parseFile: vi.fn().mockResolvedValue({ symbols: [symbol] }),
+    };
+
+    registerCodeTools(server as any, { multiLanguageParser: parser as any });
+
+    const handler = server.handlers.get('code.parseFile');
+    const result = await handler?.({ content: 'package main', language: 'Go' });
+
+    expect(parser.parseFile).toHaveBeenCalledWith('snippet.go', 'package main');
+    expect(result?.structuredContent.language).toBe('Go');
+    expect(result?.structuredContent.symbols).toEqual([symbol]);
+    expect(result?.structuredContent.diagnostics).toEqual([]);
+
+    await expect(handler?.({ content: 'x', language: 'Cobol' })).rejects.toThrow(
+      /Unsupported language: Cobol/,
+    );
+    await expect(handler?.({ language: 'Go' })).rejects.toThrow(/content or projectPath/);
+  });
+
+  it('code.parseFile detects the language of raw content and accepts hints in any case', async () => {
+    const server = new StubServer();
+    const parser = {
+      getSupportedLanguages: vi.fn().mockReturnValue(['TypeScript', 'Go']),
+      detectLanguage: vi.fn().mockReturnValue('Go'),
+      parseFile: vi.fn().mockResolvedValue({ symbols: [] }),
+    };
+
+    registerCodeTools(server as any, { multiLanguageParser: parser as any });
+
+    const handler = server.handlers.get('code.parseFile');
+    const detected = await handler?.({ content: 'package main' });
+    expect(parser.detectLanguage).toHaveBeenCalledWith('', 'package main');
+    expect(parser.parseFile).toHaveBeenLastCalledWith('snippet.go', 'package main');
+    expect(detected?.structuredContent.language).toBe('Go');
+
+    parser.detectLanguage.mockReturnValue(null);
+    const hinted = await handler?.({ content: 'const x = 1;', language: 'typescript' });
+    expect(parser.parseFile).toHaveBeenLastCalledWith('snippet.ts', 'const x = 1;');
+    expect(hinted?.structuredContent.language).toBe('TypeScript');
+
+    await expect(handler?.({ content: '???' })).rejects.toThrow(/Unsupported language: unknown/);
+  });
+
+  it('code.parseFile detects the language of a file from its content', async () => {
+    const server = new StubServer();
+    const source = '#!/usr/bin/env python3\ndef main():\n    pass\n';
+    const readFile = vi.fn().mockResolvedValue(source);
+    const parser = {
+      getSupportedLanguages: vi.fn().mockReturnValue(['Python', 'Go']),
+      detectLanguage: vi.fn().mockReturnValue('Python'),
+      parseFile: vi.fn().mockResolvedValue({ symbols: [] }),
+    };
+
+    registerCodeTools(server as any, { readFile, multiLanguageParser: parser as any });
+
+    const handler = server.handlers.get('code.parseFile');
+    const result = await handler?.({ projectPath: '/repo', file: 'bin/deploy' });
+
+    expect(readFile).toHaveBeenCalledWith('/repo/bin/deploy', 'utf8');
+    expect(parser.detectLanguage).toHaveBeenCalledWith('bin/deploy', source);
+    expect(parser.parseFile).toHaveBeenCalledWith('bin/deploy', source);
+    expect(result?.structuredContent.language).toBe('Python');
+    expect(result?.structuredContent.filePath).toBe('bin/deploy');
+  });
+
+  it('code.parseFile returns partial symbols together with diagnostics', async () => {
+    const server = new StubServer();
+    const symbol = {
+      name: 'Shout',
+      kind: 'function',
+      span: { startLine: 25, endLine: 27, startByte: 480, endByte: 550 },
+      signature: '(string)string',
+      stableId: '0123456789abcdef',
+      isExported: true,
+      contentHash: 'fedcba9876543210',
+    };
+    const diagnostic = {
+      message: 'Syntax error: unexpected `counter := 0`',
+      span: { startLine: 22, endLine: 22, startByte: 440, endByte: 452 },
+    };
+    const parser = {
+      getSupportedLanguages: vi.fn().mockReturnValue(['Go']),
+      detectLanguage: vi.fn().mockReturnValue(null),
+      parseFile: vi.fn().mockResolvedValue({ symbols: [symbol], diagnostics: [diagnostic] }),
+    };
+
+    registerCodeTools(server as any, { multiLanguageParser: parser as any });
+
+    const handler = server.handlers.get('code.parseFile');
+    const result = await handler?.({ content: 'package broken', language: 'Go' });
+
+    expect(result?.structuredContent.symbols).toEqual([symbol]);
+    expect(result?.structuredContent.diagnostics).toEqual([diagnostic]);
+    expect(result?.content[0].text).toContain('**Diagnostics**: 1');
+    expect(result?.content[0].text).toContain('L22: Syntax error');
+  });
+
   it('db.getStats returns metadata from fingerprint service', async () => {
     const server = new StubServer();
     const workspace = await mkdtemp(path.join(tmpdir(), 'db-stats-'));